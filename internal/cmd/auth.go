@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/auth"
+)
+
+const (
+	tokenAuthFileFlag       = "token-auth-file"
+	tokenAuthSecretFlag     = "token-auth-secret"
+	tokenReviewFlag         = "token-review"
+	tokenReviewAudienceFlag = "token-review-audience"
+	authorizeNamespacesFlag = "authorize-namespaces"
+)
+
+// makeAuthenticator creates an Authenticator from the configured options.
+//
+// If no authentication is configured, nil is returned.
+func makeAuthenticator(ctx context.Context, cl client.Client) (auth.Authenticator, error) {
+	authenticators := []auth.Authenticator{}
+
+	if filename := viper.GetString(tokenAuthFileFlag); filename != "" {
+		tokens, err := auth.LoadStaticTokensFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, tokens)
+	}
+
+	if raw := viper.GetString(tokenAuthSecretFlag); raw != "" {
+		namespace, name, ok := strings.Cut(raw, "/")
+		if !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("invalid --%s %q: must be namespace/name", tokenAuthSecretFlag, raw)
+		}
+		tokens, err := auth.LoadStaticTokensSecret(ctx, cl, client.ObjectKey{Namespace: namespace, Name: name})
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, tokens)
+	}
+
+	if viper.GetString(clientCAFileFlag) != "" {
+		authenticators = append(authenticators, auth.ClientCertificate{})
+	}
+
+	if viper.GetBool(tokenReviewFlag) {
+		authenticators = append(authenticators, auth.NewTokenReview(cl, viper.GetStringSlice(tokenReviewAudienceFlag)...))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, nil
+	}
+	return auth.Union(authenticators...), nil
+}
+
+// authorizationCacheTTL is how long the decision for a User and namespace is
+// reused, so that each request doesn't need a SubjectAccessReview for each
+// namespace.
+const authorizationCacheTTL = 10 * time.Second
+
+// makeAuthorizer creates an Authorizer from the configured options.
+//
+// If authorization is not enabled, nil is returned.
+func makeAuthorizer(cl client.Client, authenticator auth.Authenticator) (auth.Authorizer, error) {
+	if !viper.GetBool(authorizeNamespacesFlag) {
+		return nil, nil
+	}
+	if authenticator == nil {
+		return nil, fmt.Errorf("--%s requires authentication to be configured", authorizeNamespacesFlag)
+	}
+	if len(viper.GetStringSlice(clusterFlag)) > 0 {
+		return nil, fmt.Errorf("--%s can't be used with --%s", authorizeNamespacesFlag, clusterFlag)
+	}
+	return auth.NewCachedAuthorizer(auth.NewSubjectAccessReview(cl), authorizationCacheTTL), nil
+}
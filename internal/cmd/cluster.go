@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+const (
+	clusterFlag             = "cluster"
+	duplicateComponentsFlag = "duplicate-components"
+)
+
+func addClusterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray(
+		clusterFlag,
+		nil,
+		"discover components from this cluster e.g. name=prod,context=prod-admin,kubeconfig=/etc/kube/prod, can be repeated",
+	)
+	cmd.Flags().String(
+		duplicateComponentsFlag,
+		string(catalog.MergeDuplicates),
+		fmt.Sprintf("how components with the same name in more than one cluster are published, one of %s", strings.Join(catalog.DuplicatePolicies, ", ")),
+	)
+	for _, flag := range []string{clusterFlag, duplicateComponentsFlag} {
+		cobra.CheckErr(viper.BindPFlag(flag, cmd.Flags().Lookup(flag)))
+	}
+}
+
+// kubeCluster is a cluster that components are discovered from, the name is
+// empty if components are only discovered from the default cluster.
+type kubeCluster struct {
+	name   string
+	config *rest.Config
+}
+
+// clusterConfig is a cluster parsed from the --cluster flag.
+type clusterConfig struct {
+	name       string
+	kubeconfig string
+	context    string
+}
+
+// parseCluster parses a cluster from comma-separated key=value pairs, the
+// name is required, the kubeconfig defaults to the --kubeconfig flag and the
+// context to the current context in the kubeconfig.
+func parseCluster(s string) (clusterConfig, error) {
+	var c clusterConfig
+	keys := []string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid --%s %q, expected key=value pairs", clusterFlag, s)
+		}
+		if slices.Contains(keys, k) {
+			return c, fmt.Errorf("invalid --%s %q, key %q is set more than once", clusterFlag, s, k)
+		}
+		keys = append(keys, k)
+		switch k {
+		case "name":
+			c.name = v
+		case "kubeconfig":
+			c.kubeconfig = v
+		case "context":
+			c.context = v
+		default:
+			return c, fmt.Errorf("invalid --%s %q, unknown key %q", clusterFlag, s, k)
+		}
+	}
+	if c.name == "" {
+		return c, fmt.Errorf("invalid --%s %q, name is required", clusterFlag, s)
+	}
+	if errs := validation.IsDNS1123Label(c.name); len(errs) > 0 {
+		return c, fmt.Errorf("invalid --%s %q, invalid name: %s", clusterFlag, s, strings.Join(errs, ", "))
+	}
+	return c, nil
+}
+
+// discoveryClusters returns the clusters to discover components from, this
+// is only the default cluster if no clusters are configured.
+func discoveryClusters(defaultConfig *rest.Config) ([]kubeCluster, error) {
+	values := viper.GetStringSlice(clusterFlag)
+	if len(values) == 0 {
+		return []kubeCluster{{config: defaultConfig}}, nil
+	}
+
+	clusters := []kubeCluster{}
+	names := []string{}
+	for _, v := range values {
+		c, err := parseCluster(v)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, c.name) {
+			return nil, fmt.Errorf("invalid --%s, cluster %q is configured more than once", clusterFlag, c.name)
+		}
+		names = append(names, c.name)
+
+		kubeconfig := c.kubeconfig
+		if kubeconfig == "" {
+			kubeconfig = viper.GetString(kubeconfigFlag)
+		}
+		cfg, err := restConfigFor(kubeconfig, c.context)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %w", c.name, err)
+		}
+		clusters = append(clusters, kubeCluster{name: c.name, config: cfg})
+	}
+	return clusters, nil
+}
+
+// needsDefaultCluster returns true if the cluster from the --kubeconfig and
+// --context flags is used, components are discovered from it unless
+// clusters are configured, and it's used for authentication and loading
+// overlays from a ConfigMap.
+func needsDefaultCluster() bool {
+	return len(viper.GetStringSlice(clusterFlag)) == 0 ||
+		viper.GetBool(tokenReviewFlag) ||
+		viper.GetString(tokenAuthSecretFlag) != "" ||
+		viper.GetString(overlayConfigMapFlag) != ""
+}
+
+// duplicatePolicy returns the configured policy for components with the
+// same name in more than one cluster.
+func duplicatePolicy() (catalog.DuplicatePolicy, error) {
+	policy := viper.GetString(duplicateComponentsFlag)
+	if !slices.Contains(catalog.DuplicatePolicies, policy) {
+		return "", fmt.Errorf("invalid --%s %q, must be one of %s", duplicateComponentsFlag, policy, strings.Join(catalog.DuplicatePolicies, ", "))
+	}
+	return catalog.DuplicatePolicy(policy), nil
+}
+
+// clusterClients creates a client for each of the clusters, for listing
+// resources when serving requests.
+func clusterClients(clusters []kubeCluster) ([]catalog.Cluster, error) {
+	result := []catalog.Cluster{}
+	for _, v := range clusters {
+		cl, err := client.New(v.config, client.Options{Scheme: scheme})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for cluster %q: %w", v.name, err)
+		}
+		result = append(result, catalog.Cluster{Name: v.name, Client: cl})
+	}
+	return result, nil
+}
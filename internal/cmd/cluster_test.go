@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCluster(t *testing.T) {
+	parseTests := []struct {
+		value string
+		want  clusterConfig
+	}{
+		{
+			value: "name=dev",
+			want:  clusterConfig{name: "dev"},
+		},
+		{
+			value: "name=prod-1,kubeconfig=/etc/kube/prod-1.yaml,context=admin",
+			want:  clusterConfig{name: "prod-1", kubeconfig: "/etc/kube/prod-1.yaml", context: "admin"},
+		},
+		{
+			value: "context=dev-admin,name=dev",
+			want:  clusterConfig{name: "dev", context: "dev-admin"},
+		},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseCluster(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(clusterConfig{})); diff != "" {
+				t.Errorf("got cluster:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseClusterErrors(t *testing.T) {
+	parseTests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "missing name",
+			value: "context=dev-admin",
+			want:  `invalid --cluster "context=dev-admin", name is required`,
+		},
+		{
+			name:  "empty name",
+			value: "name=,context=dev-admin",
+			want:  `invalid --cluster "name=,context=dev-admin", name is required`,
+		},
+		{
+			name:  "duplicate key",
+			value: "name=dev,context=dev-admin,context=prod-admin",
+			want:  `invalid --cluster "name=dev,context=dev-admin,context=prod-admin", key "context" is set more than once`,
+		},
+		{
+			name:  "duplicate name",
+			value: "name=dev,name=prod",
+			want:  `invalid --cluster "name=dev,name=prod", key "name" is set more than once`,
+		},
+		{
+			name:  "unknown key",
+			value: "name=dev,namespace=default",
+			want:  `invalid --cluster "name=dev,namespace=default", unknown key "namespace"`,
+		},
+		{
+			name:  "not key value pairs",
+			value: "dev",
+			want:  `invalid --cluster "dev", expected key=value pairs`,
+		},
+		{
+			name:  "invalid DNS label",
+			value: "name=Prod_1",
+			want:  `invalid --cluster "name=Prod_1", invalid name: a lowercase RFC 1123 label`,
+		},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCluster(tt.value)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/config"
+)
+
+const (
+	configFlag = "config"
+
+	// envPrefix is the prefix for environment variables that configure
+	// settings e.g. PEANUT_BACKSTAGE_MAX_STALENESS.
+	envPrefix = "PEANUT_BACKSTAGE"
+
+	// configReloadInterval is how often the config file is checked for
+	// changes.
+	configReloadInterval = 10 * time.Second
+)
+
+// reloadableSettings are applied when the configuration is reloaded, changes
+// to other settings require a restart.
+var reloadableSettings = []string{debugFlag, overlayFlag, overlayConfigMapFlag}
+
+// sharedSettings are the settings that more than one command has flags for,
+// they're bound to the flags of the command that is run by
+// bindSharedSettings.
+var sharedSettings = []string{
+	configFlag,
+	exportSelectorFlag,
+	locationNameFlag,
+	locationDescriptionFlag,
+	overlayFlag,
+	overlayConfigMapFlag,
+}
+
+// addConfigFlag adds the flag for reading settings from a config file to
+// commands other than serve.
+func addConfigFlag(cmd *cobra.Command) {
+	cmd.Flags().String(
+		configFlag,
+		"",
+		"read settings from this YAML file, using the names of the flags as keys, the file can also contain settings for serve",
+	)
+}
+
+// bindSharedSettings binds the shared settings to the flags of the command
+// that is run, and loads the config file if the command has one, so that
+// every command reads them from the flags, environment variables and config
+// file in the same way.
+func bindSharedSettings(cmd *cobra.Command, args []string) error {
+	for _, flag := range sharedSettings {
+		if f := cmd.Flags().Lookup(flag); f != nil {
+			if err := viper.BindPFlag(flag, f); err != nil {
+				return err
+			}
+		}
+	}
+	if cmd.Flags().Lookup(configFlag) == nil {
+		return nil
+	}
+	if filename := viper.GetString(configFlag); filename != "" {
+		return loadConfig(configFlags(cmd), filename)
+	}
+	return nil
+}
+
+// configFlags returns the flags that the config file is validated against,
+// the settings for serve are accepted by every command so that the same
+// file can be used for all of them.
+func configFlags(cmd *cobra.Command) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(cmd.Flags())
+	if serve, _, err := cmd.Root().Find([]string{"serve"}); err == nil && serve != cmd.Root() {
+		flags.AddFlagSet(serve.Flags())
+	}
+	return flags
+}
+
+// loadConfig reads and validates the config file, and uses it for the
+// settings that aren't set with flags or environment variables.
+func loadConfig(flags *pflag.FlagSet, filename string) error {
+	if _, err := config.Read(filename, flags); err != nil {
+		return err
+	}
+	viper.SetConfigFile(filename)
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", filename, err)
+	}
+	return nil
+}
+
+// readSettings reads the settings from the flags, environment variables and
+// config file into a new Viper, with the same precedence as the global
+// Viper.
+//
+// Reloads use this rather than updating the global Viper, which isn't safe
+// while the settings are being read elsewhere.
+func readSettings(flags *pflag.FlagSet, filename string) (*viper.Viper, error) {
+	if _, err := config.Read(filename, flags); err != nil {
+		return nil, err
+	}
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+	if err := v.BindPFlags(flags); err != nil {
+		return nil, err
+	}
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+	}
+	if err := config.CheckRanges(v, flags); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// watchConfig calls apply with the settings to apply the reloadable settings
+// when the process receives SIGHUP, and if a config file is provided,
+// rereads the file when it changes or on SIGHUP before calling apply.
+//
+// The global Viper is only read when this is called, reloaded settings are
+// passed to apply. Changes to settings that can't be reloaded are logged.
+func watchConfig(ctx context.Context, logger logr.Logger, flags *pflag.FlagSet, filename string, apply func(*viper.Viper) error) error {
+	logger = logger.WithName("config")
+	current := viper.GetViper()
+	reload := func() {
+		if err := apply(current); err != nil {
+			logger.Error(err, "failed to reload configuration")
+			return
+		}
+		logger.Info("reloaded configuration")
+	}
+
+	if filename != "" {
+		initial, err := readSettings(flags, filename)
+		if err != nil {
+			return err
+		}
+		before := settings(initial, flags)
+		w, err := config.NewWatcher(logger, filename, func() error {
+			v, err := readSettings(flags, filename)
+			if err != nil {
+				return err
+			}
+			after := settings(v, flags)
+			for _, key := range slices.Sorted(maps.Keys(before)) {
+				if !slices.Contains(reloadableSettings, key) && !reflect.DeepEqual(before[key], after[key]) {
+					logger.Info("restart required to apply changed setting", "setting", key)
+				}
+			}
+			before = after
+			return apply(v)
+		})
+		if err != nil {
+			return err
+		}
+		go w.Start(ctx, configReloadInterval)
+		reload = w.Trigger
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reload()
+			}
+		}
+	}()
+	return nil
+}
+
+// settings returns the values in v of the settings for the flags.
+func settings(v *viper.Viper, flags *pflag.FlagSet) map[string]any {
+	result := map[string]any{}
+	flags.VisitAll(func(f *pflag.Flag) {
+		result[f.Name] = v.Get(f.Name)
+	})
+	return result
+}
+
+// logLevel returns the level to log at.
+func logLevel(debug bool) zapcore.Level {
+	if debug {
+		return zapcore.DebugLevel
+	}
+	return zapcore.InfoLevel
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestWatchConfigReloadsOnSIGHUP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	applied := make(chan *viper.Viper, 1)
+
+	if err := watchConfig(ctx, logr.Discard(), testFlags(), "", func(v *viper.Viper) error {
+		applied <- v
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sighup(t)
+
+	waitForApply(t, applied)
+}
+
+func TestWatchConfigReloadsFile(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	flags := testFlags()
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, filename, "listen: :9080\ndebug: false\n")
+	if err := loadConfig(flags, filename); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, args)
+	}, funcr.Options{})
+	applied := make(chan *viper.Viper, 1)
+
+	if err := watchConfig(ctx, logger, flags, filename, func(v *viper.Viper) error {
+		applied <- v
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filename, "listen: :9090\ndebug: true\n")
+	sighup(t)
+
+	v := waitForApply(t, applied)
+	if !v.GetBool(debugFlag) {
+		t.Error("debug setting was not reloaded")
+	}
+	if viper.GetBool(debugFlag) {
+		t.Error("global settings were updated by the reload")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := `"msg"="restart required to apply changed setting" "setting"="listen"`
+	if !strings.Contains(strings.Join(logs, "\n"), want) {
+		t.Errorf("got logs %q, want %q", logs, want)
+	}
+}
+
+func TestWatchConfigRejectsInvalidFile(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	flags := testFlags()
+	flags.Int(changeLogSizeFlag, 1000, "")
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, filename, "change-log-size: 100\n")
+	if err := loadConfig(flags, filename); err != nil {
+		t.Fatal(err)
+	}
+	failed := make(chan string, 1)
+	logger := funcr.New(func(prefix, args string) {
+		if strings.Contains(args, "failed to reload config file") {
+			failed <- args
+		}
+	}, funcr.Options{})
+
+	if err := watchConfig(ctx, logger, flags, filename, func(*viper.Viper) error {
+		t.Error("invalid configuration was applied")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filename, "change-log-size: -1\n")
+	sighup(t)
+
+	select {
+	case args := <-failed:
+		if !strings.Contains(args, "must not be negative") {
+			t.Errorf("got log %q", args)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalid configuration was not rejected")
+	}
+	if got := viper.GetInt(changeLogSizeFlag); got != 100 {
+		t.Errorf("got change-log-size %d, want 100", got)
+	}
+}
+
+func TestWatchConfigMissingFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "missing.yaml")
+
+	err := watchConfig(context.Background(), logr.Discard(), testFlags(), filename, func(*viper.Viper) error { return nil })
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to read config file "+filename) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestCommandsReadSharedSettings(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "deployment.yaml")
+	writeFile(t, manifest, testManifest)
+	catalogFile := filepath.Join(dir, "catalog-info.yaml")
+	writeFile(t, catalogFile, "apiVersion: backstage.io/v1alpha1\nkind: Component\nmetadata:\n  name: nginx\n")
+
+	commandTests := []struct {
+		name string
+		args []string
+	}{
+		{name: "generate", args: []string{"generate", "-f", manifest}},
+		{name: "lint", args: []string{"lint", "-f", manifest}},
+		{name: "lint cluster", args: []string{"lint"}},
+		{name: "explain", args: []string{"explain", "deployment/nginx", "-f", manifest}},
+		{name: "diff", args: []string{"diff", catalogFile, "-f", manifest}},
+	}
+
+	for _, tt := range commandTests {
+		t.Run(tt.name+" environment variable", func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			t.Setenv("PEANUT_BACKSTAGE_EXPORT_SELECTOR", "tier in (")
+
+			assertInvalidSelector(t, executeRoot(t, tt.args...))
+		})
+
+		t.Run(tt.name+" config file", func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			filename := filepath.Join(t.TempDir(), "config.yaml")
+			writeFile(t, filename, "listen: :9080\nexport-selector: tier in (\n")
+
+			assertInvalidSelector(t, executeRoot(t, append(tt.args, "--config", filename)...))
+		})
+	}
+}
+
+func TestGenerateReadsOverlaysFromConfig(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "deployment.yaml")
+	writeFile(t, manifest, testManifest)
+	overlay := filepath.Join(dir, "overlay.yaml")
+	writeFile(t, overlay, "apiVersion: backstage.io/v1alpha1\nkind: Component\nmetadata:\n  name: nginx\nspec:\n  owner: team-b\n")
+	filename := filepath.Join(dir, "config.yaml")
+	writeFile(t, filename, "overlay:\n  - "+overlay+"\n")
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"generate", "-f", manifest, "--config", filename})
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "owner: team-b") {
+		t.Fatalf("overlay was not applied:\n%s", out)
+	}
+}
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  labels:
+    app.kubernetes.io/name: nginx
+    app.kubernetes.io/created-by: team-a
+`
+
+func executeRoot(t *testing.T, args ...string) error {
+	t.Helper()
+	cmd := newRootCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	return cmd.Execute()
+}
+
+func assertInvalidSelector(t *testing.T, err error) {
+	t.Helper()
+	want := "invalid --export-selector"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
+
+func sighup(t *testing.T) {
+	t.Helper()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitForApply(t *testing.T, applied chan *viper.Viper) *viper.Viper {
+	t.Helper()
+	select {
+	case v := <-applied:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration was not applied")
+	}
+	return nil
+}
+
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String(listenFlag, "localhost:8080", "")
+	flags.Bool(debugFlag, false, "")
+	return flags
+}
+
+func writeFile(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
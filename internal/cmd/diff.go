@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/drift"
+)
+
+// errDriftFound is returned when differences are found, so that the command
+// exits with a non-zero status after they have been reported.
+var errDriftFound = errors.New("catalog files differ from the generated components")
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff CATALOG-FILE...",
+		Short: "Compare catalog files with the generated components",
+		Long: `Compare the components in existing Backstage catalog files e.g.
+catalog-info.yaml, with the components that are generated from resources,
+and report the fields that differ.
+
+Directories of catalog files are read recursively, files in them that can't
+be decoded are skipped with a warning. Components are generated
+from the manifests in --filename, or from the cluster if no files are
+provided.
+
+The command exits with a non-zero status if any differences are found.`,
+		Example: `  peanut-backstage diff catalog-info.yaml
+  peanut-backstage diff ./catalog -f ./manifests -o json`,
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString(outputFlag)
+			if !slices.Contains(drift.Formats, format) {
+				return fmt.Errorf("invalid --%s %q, must be one of %s", outputFlag, format, strings.Join(drift.Formats, ", "))
+			}
+
+			files, err := drift.Read(args)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				if f.Err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", f.Err)
+				}
+			}
+
+			var objs []unstructured.Unstructured
+			if paths, _ := cmd.Flags().GetStringSlice(filenameFlag); len(paths) > 0 {
+				objs, err = readManifests(cmd)
+			} else {
+				objs, err = listClusterObjects(cmd)
+			}
+			if err != nil {
+				return err
+			}
+			components, err := parseWithOverlays(cmd, objs)
+			if err != nil {
+				return err
+			}
+
+			diffs := drift.Compare(files, components)
+			if err := drift.Write(cmd.OutOrStdout(), format, diffs); err != nil {
+				return err
+			}
+			if len(diffs) > 0 {
+				return errDriftFound
+			}
+			return nil
+		},
+	}
+
+	addManifestFlags(cmd)
+	addOverlayFlags(cmd, false)
+	cmd.Flags().StringP(
+		outputFlag,
+		"o",
+		drift.FormatText,
+		fmt.Sprintf("output format, one of %s", strings.Join(drift.Formats, ", ")),
+	)
+	return cmd
+}
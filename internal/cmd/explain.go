@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+const (
+	explainFormatText = "text"
+	explainFormatJSON = "json"
+)
+
+func newExplainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain KIND/[NAMESPACE/]NAME",
+		Short: "Explain how the fields of a resource's component were derived",
+		Long: `Print each field of the Backstage component for a resource, with the
+label, annotation or default that its value came from.
+
+The other resources that contributed to the same component are listed, along
+with any values that were overridden when they were merged.
+
+Resources are read from the manifests in --filename, or from the cluster if
+no files are provided.`,
+		Example: `  peanut-backstage explain deployment/production/nginx
+  peanut-backstage explain deployment/production/nginx -f ./manifests -o json`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString(outputFlag)
+			if format != explainFormatText && format != explainFormatJSON {
+				return fmt.Errorf("invalid --%s %q, must be one of %s, %s", outputFlag, format, explainFormatText, explainFormatJSON)
+			}
+			target, err := parseObjectReference(args[0])
+			if err != nil {
+				return err
+			}
+
+			var objs []unstructured.Unstructured
+			if paths, _ := cmd.Flags().GetStringSlice(filenameFlag); len(paths) > 0 {
+				objs, err = readManifests(cmd)
+			} else {
+				objs, err = listClusterObjects(cmd)
+			}
+			if err != nil {
+				return err
+			}
+
+			explanation, err := explainObject(objs, target)
+			if err != nil {
+				return err
+			}
+			if format == explainFormatJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(explanation)
+			}
+			return writeExplanation(cmd.OutOrStdout(), explanation)
+		},
+	}
+
+	addManifestFlags(cmd)
+	cmd.Flags().StringP(
+		outputFlag,
+		"o",
+		explainFormatText,
+		fmt.Sprintf("output format, one of %s, %s", explainFormatText, explainFormatJSON),
+	)
+	return cmd
+}
+
+// parseObjectReference parses a reference in the form KIND/NAMESPACE/NAME,
+// or KIND/NAME for manifests that don't have a namespace.
+func parseObjectReference(s string) (backstage.ObjectReference, error) {
+	parts := strings.Split(s, "/")
+	if slices.Contains(parts, "") {
+		parts = nil
+	}
+	switch len(parts) {
+	case 2:
+		return backstage.ObjectReference{Kind: parts[0], Name: parts[1]}, nil
+	case 3:
+		return backstage.ObjectReference{Kind: parts[0], Namespace: parts[1], Name: parts[2]}, nil
+	}
+	return backstage.ObjectReference{}, fmt.Errorf("invalid resource %q, must be in the form KIND/NAMESPACE/NAME", s)
+}
+
+// explainObject explains the Component for the target, from all the objects
+// that contribute to the same Component.
+func explainObject(objs []unstructured.Unstructured, target backstage.ObjectReference) (backstage.Explanation, error) {
+	var found *unstructured.Unstructured
+	for i, obj := range objs {
+		if catalog.IsDiscovered(obj) && strings.EqualFold(obj.GetKind(), target.Kind) &&
+			obj.GetNamespace() == target.Namespace && obj.GetName() == target.Name {
+			found = &objs[i]
+			break
+		}
+	}
+	if found == nil {
+		return backstage.Explanation{}, fmt.Errorf("resource %s not found", target)
+	}
+	name := found.GetLabels()[backstage.NameLabel]
+	if name == "" {
+		return backstage.Explanation{}, fmt.Errorf("resource %s has no %s label, it is not published as a Component", target, backstage.NameLabel)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	for _, obj := range objs {
+		if catalog.IsDiscovered(obj) && obj.GetLabels()[backstage.NameLabel] == name {
+			list.Items = append(list.Items, obj)
+		}
+	}
+	parser := backstage.NewComponentParser()
+	if err := parser.Add(list); err != nil {
+		return backstage.Explanation{}, fmt.Errorf("failed to parse deployments: %w", err)
+	}
+	explanation, _ := parser.Explain(name)
+	return explanation, nil
+}
+
+func writeExplanation(out io.Writer, e backstage.Explanation) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Component:\t%s\n\n", e.Component)
+
+	fmt.Fprintln(w, "FIELD\tVALUE\tSOURCE\tOBJECT")
+	for _, f := range e.Fields {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Field, f.Value, f.Source, objectString(f.Object))
+	}
+
+	fmt.Fprintln(w, "\nObjects:")
+	for _, ref := range e.Objects {
+		fmt.Fprintf(w, "  %s\n", ref)
+	}
+
+	if len(e.Overrides) > 0 {
+		fmt.Fprintln(w, "\nOverrides:")
+		fmt.Fprintln(w, "FIELD\tPREVIOUS\tFROM\tCURRENT\tFROM")
+		for _, o := range e.Overrides {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", o.Field,
+				o.Previous.Value, objectString(o.Previous.Object),
+				o.Current.Value, objectString(o.Current.Object))
+		}
+	}
+	return w.Flush()
+}
+
+func objectString(ref *backstage.ObjectReference) string {
+	if ref == nil {
+		return "-"
+	}
+	return ref.String()
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestParseObjectReference(t *testing.T) {
+	parseTests := []struct {
+		value string
+		want  backstage.ObjectReference
+	}{
+		{
+			value: "deployment/production/nginx",
+			want:  backstage.ObjectReference{Kind: "deployment", Namespace: "production", Name: "nginx"},
+		},
+		{
+			value: "Deployment/nginx",
+			want:  backstage.ObjectReference{Kind: "Deployment", Name: "nginx"},
+		},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseObjectReference(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("got reference:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseObjectReferenceErrors(t *testing.T) {
+	parseTests := []struct {
+		name  string
+		value string
+	}{
+		{name: "name only", value: "nginx"},
+		{name: "too many parts", value: "deployment/production/nginx/extra"},
+		{name: "empty namespace", value: "deployment//nginx"},
+		{name: "empty name", value: "deployment/"},
+		{name: "empty kind", value: "/production/nginx"},
+		{name: "empty", value: ""},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseObjectReference(tt.value)
+
+			want := `invalid resource "` + tt.value + `", must be in the form KIND/NAMESPACE/NAME`
+			if err == nil || err.Error() != want {
+				t.Fatalf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestExplainObject(t *testing.T) {
+	objs := []unstructured.Unstructured{
+		toUnstructured(t, test.NewDeployment("nginx-blue", "web",
+			test.WithLabels(map[string]string{backstage.NameLabel: "nginx"}))),
+		toUnstructured(t, test.NewDeployment("nginx-green", "web",
+			test.WithLabels(map[string]string{backstage.NameLabel: "nginx"}))),
+		toUnstructured(t, test.NewDeployment("mysql", "web",
+			test.WithLabels(map[string]string{backstage.NameLabel: "mysql"}))),
+	}
+
+	explanation, err := explainObject(objs, backstage.ObjectReference{Kind: "deployment", Namespace: "web", Name: "nginx-green"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if explanation.Component != "nginx" {
+		t.Errorf("got component %q, want %q", explanation.Component, "nginx")
+	}
+	want := []backstage.ObjectReference{
+		{Kind: "Deployment", Namespace: "web", Name: "nginx-blue"},
+		{Kind: "Deployment", Namespace: "web", Name: "nginx-green"},
+	}
+	if diff := cmp.Diff(want, explanation.Objects); diff != "" {
+		t.Errorf("got objects:\n%s", diff)
+	}
+}
+
+func TestExplainObjectErrors(t *testing.T) {
+	service := unstructured.Unstructured{}
+	service.SetAPIVersion("v1")
+	service.SetKind("Service")
+	service.SetNamespace("web")
+	service.SetName("nginx")
+	service.SetLabels(map[string]string{backstage.NameLabel: "nginx"})
+	objs := []unstructured.Unstructured{
+		toUnstructured(t, test.NewDeployment("unlabelled", "web")),
+		service,
+	}
+
+	explainTests := []struct {
+		name   string
+		target backstage.ObjectReference
+		want   string
+	}{
+		{
+			name:   "not found",
+			target: backstage.ObjectReference{Kind: "deployment", Namespace: "web", Name: "missing"},
+			want:   "resource deployment/web/missing not found",
+		},
+		{
+			name:   "wrong namespace",
+			target: backstage.ObjectReference{Kind: "deployment", Name: "unlabelled"},
+			want:   "resource deployment/unlabelled not found",
+		},
+		{
+			name:   "not discovered",
+			target: backstage.ObjectReference{Kind: "service", Namespace: "web", Name: "nginx"},
+			want:   "resource service/web/nginx not found",
+		},
+		{
+			name:   "no name label",
+			target: backstage.ObjectReference{Kind: "deployment", Namespace: "web", Name: "unlabelled"},
+			want:   "resource deployment/web/unlabelled has no app.kubernetes.io/name label, it is not published as a Component",
+		},
+	}
+
+	for _, tt := range explainTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := explainObject(objs, tt.target)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func toUnstructured(t *testing.T, dep appsv1.Deployment) unstructured.Unstructured {
+	t.Helper()
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&dep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unstructured.Unstructured{Object: obj}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/export"
+	"github.com/bigkevmcd/peanut-backstage/pkg/httpapi"
+)
+
+const (
+	outputDirFlag           = "output-dir"
+	outputFileFlag          = "output-file"
+	locationNameFlag        = "location-name"
+	locationDescriptionFlag = "location-description"
+)
+
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the Backstage catalog to files",
+		Long: `Discover components in the cluster and write them to files that can be
+committed to a repository that Backstage reads.
+
+By default, a catalog-info.yaml Location is written with a file for each
+component, matching the targets served by the serve command, with
+--output-file the components are written to a single file instead.
+
+Files whose content hasn't changed are not rewritten.
+
+The settings shared with the serve command can be read from the same config
+file and environment variables.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exportSelector, err := labels.Parse(viper.GetString(exportSelectorFlag))
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %w", exportSelectorFlag, err)
+			}
+
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			overlays, err := loadOverlays(cmd.Context(), cl, viper.GetStringSlice(overlayFlag), viper.GetString(overlayConfigMapFlag))
+			if err != nil {
+				return err
+			}
+
+			list, err := catalog.ListNamespaces(cmd.Context(), cl, namespaces(), client.MatchingLabelsSelector{Selector: exportSelector})
+			if err != nil {
+				return err
+			}
+			components, err := catalog.Parse(list)
+			if err != nil {
+				return err
+			}
+			components = overlays.Apply(components)
+
+			if outputFile, _ := cmd.Flags().GetString(outputFileFlag); outputFile != "" {
+				b, err := export.MultiDocument(components)
+				if err != nil {
+					return err
+				}
+				written, err := export.WriteFile(outputFile, b)
+				if err != nil {
+					return err
+				}
+				if written {
+					fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", outputFile)
+				}
+				return nil
+			}
+
+			files, err := export.Files(viper.GetString(locationNameFlag), viper.GetString(locationDescriptionFlag), components)
+			if err != nil {
+				return err
+			}
+			dir, _ := cmd.Flags().GetString(outputDirFlag)
+			changed, err := export.WriteDir(dir, files)
+			if err != nil {
+				return err
+			}
+			for _, v := range changed {
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", filepath.Join(dir, filepath.FromSlash(v)))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String(
+		outputDirFlag,
+		".",
+		"directory to write catalog-info.yaml and the component files to",
+	)
+	cmd.Flags().String(
+		outputFileFlag,
+		"",
+		"write all the components to this file as YAML documents instead of a directory",
+	)
+	cmd.Flags().String(
+		locationNameFlag,
+		httpapi.DefaultLocationName,
+		"name of the Location in catalog-info.yaml",
+	)
+	cmd.Flags().String(
+		locationDescriptionFlag,
+		httpapi.DefaultLocationDescription,
+		"description of the Location in catalog-info.yaml",
+	)
+	cmd.Flags().String(
+		exportSelectorFlag,
+		"",
+		fmt.Sprintf("only export resources matching this label selector e.g. %s=true", backstage.ExportLabel),
+	)
+	addConfigFlag(cmd)
+	addOverlayFlags(cmd, true)
+	cmd.MarkFlagsMutuallyExclusive(outputDirFlag, outputFileFlag)
+	return cmd
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExportReadsSharedSettings(t *testing.T) {
+	exportTests := []struct {
+		name   string
+		env    string
+		config string
+	}{
+		{
+			name: "environment variable",
+			env:  "tier in (",
+		},
+		{
+			name:   "config file shared with serve",
+			config: "listen: :9080\nexport-selector: tier in (\n",
+		},
+	}
+
+	for _, tt := range exportTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			args := []string{"export"}
+			if tt.env != "" {
+				t.Setenv("PEANUT_BACKSTAGE_EXPORT_SELECTOR", tt.env)
+			}
+			if tt.config != "" {
+				filename := filepath.Join(t.TempDir(), "config.yaml")
+				writeFile(t, filename, tt.config)
+				args = append(args, "--config", filename)
+			}
+			cmd := newRootCmd()
+			cmd.SetArgs(args)
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+
+			err := cmd.Execute()
+
+			want := "invalid --export-selector"
+			if err == nil || !strings.HasPrefix(err.Error(), want) {
+				t.Fatalf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestExportRejectsUnknownSettings(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, filename, "unknown: true\n")
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"export", "--config", filename})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+
+	want := `invalid config file ` + filename + `: unknown setting "unknown"`
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/fixture"
+	"github.com/bigkevmcd/peanut-backstage/pkg/metrics"
+)
+
+const fromFixtureFlag = "from-fixture"
+
+func newRecordCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record the resources in the cluster to a fixture file",
+		Long: `Record the metadata of the resources that components are discovered from
+to a single fixture file, which can be served with "serve --from-fixture"
+without a cluster e.g. for demos, developing Backstage plugins and
+reproducing bugs.
+
+Only the names, namespaces, labels and annotations of the resources are
+recorded. The fixture is a List, so it can also be read as manifests e.g.
+with "generate -f".`,
+		Example: `  peanut-backstage record --output-file fixture.yaml
+  peanut-backstage record --namespaces team-a > fixture.yaml`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			list, err := catalog.ListNamespaces(cmd.Context(), cl, namespaces())
+			if err != nil {
+				return err
+			}
+			objs, err := fixture.Record(scheme, list)
+			if err != nil {
+				return err
+			}
+
+			outputFile, _ := cmd.Flags().GetString(outputFileFlag)
+			if outputFile == "" {
+				return fixture.Write(cmd.OutOrStdout(), objs)
+			}
+			return writeFixture(outputFile, objs)
+		},
+	}
+
+	cmd.Flags().String(
+		outputFileFlag,
+		"",
+		"write the fixture to this file instead of stdout",
+	)
+	return cmd
+}
+
+func writeFixture(filename string, objs []unstructured.Unstructured) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create fixture: %w", err)
+	}
+	if err := fixture.Write(f, objs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fixtureClient creates a client that serves the objects in the fixture
+// file.
+func fixtureClient(filename string) (client.Client, error) {
+	objs, err := fixture.Read(filename)
+	if err != nil {
+		return nil, err
+	}
+	return fixture.NewClient(scheme, objs)
+}
+
+// checkFixtureSettings returns an error if settings that need a cluster are
+// used when serving from a fixture.
+func checkFixtureSettings() error {
+	conflicts := map[string]bool{
+		clusterFlag:             len(viper.GetStringSlice(clusterFlag)) > 0,
+		tokenReviewFlag:         viper.GetBool(tokenReviewFlag),
+		tokenAuthSecretFlag:     viper.GetString(tokenAuthSecretFlag) != "",
+		authorizeNamespacesFlag: viper.GetBool(authorizeNamespacesFlag),
+		overlayConfigMapFlag:    viper.GetString(overlayConfigMapFlag) != "",
+	}
+	for _, flag := range []string{clusterFlag, tokenReviewFlag, tokenAuthSecretFlag, authorizeNamespacesFlag, overlayConfigMapFlag} {
+		if conflicts[flag] {
+			return fmt.Errorf("--%s can't be used with --%s", flag, fromFixtureFlag)
+		}
+	}
+	return nil
+}
+
+// replayCatalog starts a Refresher that records the components discovered
+// from the fixture client in the ChangeLog.
+//
+// The fixture doesn't change, so the catalog is only refreshed when it's
+// triggered e.g. when the overlays are reloaded.
+func replayCatalog(ctx context.Context, logger logr.Logger, cl client.Reader, changes *catalog.ChangeLog, m *metrics.Metrics, overlays *catalog.Overlays, opts ...client.ListOption) *catalog.Refresher {
+	refresher := catalog.NewRefresher(logger.WithName("refresher"), cl, changes, opts...)
+	refresher.Metrics = m
+	refresher.Overlays = overlays
+	go func() {
+		_ = refresher.Start(ctx)
+	}()
+	return refresher
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestCheckFixtureSettings(t *testing.T) {
+	checkTests := []struct {
+		name  string
+		key   string
+		value any
+		want  string
+	}{
+		{name: "no conflicts", key: listenFlag, value: ":9080"},
+		{name: "cluster", key: clusterFlag, value: []string{"name=dev"}, want: "--cluster can't be used with --from-fixture"},
+		{name: "token review", key: tokenReviewFlag, value: true, want: "--token-review can't be used with --from-fixture"},
+		{name: "token auth secret", key: tokenAuthSecretFlag, value: "tokens", want: "--token-auth-secret can't be used with --from-fixture"},
+		{name: "authorize namespaces", key: authorizeNamespacesFlag, value: true, want: "--authorize-namespaces can't be used with --from-fixture"},
+		{name: "overlay configmap", key: overlayConfigMapFlag, value: "overlays", want: "--overlay-configmap can't be used with --from-fixture"},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(viper.Reset)
+			viper.Set(tt.key, tt.value)
+
+			err := checkFixtureSettings()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/export"
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+const filenameFlag = "filename"
+
+func newGenerateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate -f FILENAME",
+		Short: "Print the Backstage components for local manifests",
+		Long: `Parse YAML or JSON manifests from files, directories or stdin, and print
+the Backstage components that would be published for them, without
+connecting to a cluster.`,
+		Example: `  peanut-backstage generate -f ./manifests
+  kustomize build overlays/production | peanut-backstage generate -f -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			objs, err := readManifests(cmd)
+			if err != nil {
+				return err
+			}
+			components, err := parseWithOverlays(cmd, objs)
+			if err != nil {
+				return err
+			}
+			b, err := export.MultiDocument(components)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(b)
+			return err
+		},
+	}
+
+	addManifestFlags(cmd)
+	addOverlayFlags(cmd, false)
+	return cmd
+}
+
+// parseWithOverlays parses the objects into Components, and merges the
+// overlays from the files in the flags onto them.
+func parseWithOverlays(cmd *cobra.Command, objs []unstructured.Unstructured) ([]backstage.Component, error) {
+	overlays, err := loadOverlays(cmd.Context(), nil, viper.GetStringSlice(overlayFlag), "")
+	if err != nil {
+		return nil, err
+	}
+	components, err := catalog.ParseObjects(objs)
+	if err != nil {
+		return nil, err
+	}
+	return overlays.Apply(components), nil
+}
+
+// addManifestFlags adds the flags for reading manifests with
+// readManifests, and for the config file the export selector can be read
+// from.
+func addManifestFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceP(
+		filenameFlag,
+		"f",
+		nil,
+		"files or directories containing manifests, - reads from stdin",
+	)
+	cmd.Flags().String(
+		exportSelectorFlag,
+		"",
+		fmt.Sprintf("only use resources matching this label selector e.g. %s=true", backstage.ExportLabel),
+	)
+	addConfigFlag(cmd)
+}
+
+// readManifests reads the manifests from the files in the flags, and
+// returns the objects that match the export selector.
+func readManifests(cmd *cobra.Command) ([]unstructured.Unstructured, error) {
+	paths, _ := cmd.Flags().GetStringSlice(filenameFlag)
+	if len(paths) == 0 {
+		return nil, errors.New("at least one --filename is required")
+	}
+	sources, err := readManifestSources(cmd, paths)
+	if err != nil {
+		return nil, err
+	}
+	result := []unstructured.Unstructured{}
+	for _, v := range sources {
+		result = append(result, v.Objects...)
+	}
+	return result, nil
+}
+
+// readManifestSources reads the manifests from the paths, keeping only the
+// objects that match the export selector in the flags.
+func readManifestSources(cmd *cobra.Command, paths []string) ([]manifests.Source, error) {
+	exportSelector, err := labels.Parse(viper.GetString(exportSelectorFlag))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", exportSelectorFlag, err)
+	}
+
+	sources, err := manifests.ReadSources(paths, cmd.InOrStdin())
+	if err != nil {
+		return nil, err
+	}
+	for i, src := range sources {
+		matching := []unstructured.Unstructured{}
+		for _, v := range src.Objects {
+			if exportSelector.Matches(labels.Set(v.GetLabels())) {
+				matching = append(matching, v)
+			}
+		}
+		sources[i].Objects = matching
+	}
+	return sources, nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	kubeconfigFlag = "kubeconfig"
+	contextFlag    = "context"
+	namespacesFlag = "namespaces"
+)
+
+// addKubeFlags adds the flags for connecting to a cluster to all the
+// commands.
+func addKubeFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String(
+		kubeconfigFlag,
+		"",
+		"path to the kubeconfig file, defaults to $KUBECONFIG, ~/.kube/config or the in-cluster configuration",
+	)
+	cmd.PersistentFlags().String(
+		contextFlag,
+		"",
+		"the kubeconfig context to use",
+	)
+	cmd.PersistentFlags().StringSlice(
+		namespacesFlag,
+		nil,
+		"only discover resources in these namespaces, this only needs permissions in the namespaces",
+	)
+	for _, flag := range []string{kubeconfigFlag, contextFlag, namespacesFlag} {
+		cobra.CheckErr(viper.BindPFlag(flag, cmd.PersistentFlags().Lookup(flag)))
+	}
+}
+
+// restConfig returns the configuration for connecting to the cluster, from
+// the kubeconfig and context flags if they're provided.
+func restConfig() (*rest.Config, error) {
+	return restConfigFor(viper.GetString(kubeconfigFlag), viper.GetString(contextFlag))
+}
+
+// restConfigFor returns the configuration for connecting to a cluster with
+// the kubeconfig and context, or the default configuration if neither is
+// provided.
+func restConfigFor(kubeconfig, kubeContext string) (*rest.Config, error) {
+	if kubeconfig == "" && kubeContext == "" {
+		return config.GetConfig()
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// newClient creates a client for the cluster.
+func newClient() (client.Client, error) {
+	cfg, err := restConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// namespaces returns the namespaces that resources are discovered in, all
+// namespaces are used if this is empty.
+func namespaces() []string {
+	return viper.GetStringSlice(namespacesFlag)
+}
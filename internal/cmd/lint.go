@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/lint"
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+const outputFlag = "output"
+
+// errLintFailed is returned when errors are found, so that the command
+// exits with a non-zero status after the findings have been reported.
+var errLintFailed = errors.New("catalog problems were found")
+
+func newLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [-f FILENAME]",
+		Short: "Check resources for problems publishing them to Backstage",
+		Long: `Check the labels and annotations on resources for problems that would
+prevent their components from being published to Backstage correctly.
+
+Resources are read from the manifests in --filename, or from the cluster if
+no files are provided.
+
+The command exits with a non-zero status if any errors are found.`,
+		Example: `  peanut-backstage lint -f ./manifests
+  kustomize build overlays/production | peanut-backstage lint -f - -o sarif`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString(outputFlag)
+			if !slices.Contains(lint.Formats, format) {
+				return fmt.Errorf("invalid --%s %q, must be one of %s", outputFlag, format, strings.Join(lint.Formats, ", "))
+			}
+
+			var sources []manifests.Source
+			paths, _ := cmd.Flags().GetStringSlice(filenameFlag)
+			if len(paths) > 0 {
+				var err error
+				sources, err = readManifestSources(cmd, paths)
+				if err != nil {
+					return err
+				}
+			} else {
+				objs, err := listClusterObjects(cmd)
+				if err != nil {
+					return err
+				}
+				sources = []manifests.Source{{Objects: objs}}
+			}
+
+			findings := lint.Lint(sources)
+			if err := lint.Write(cmd.OutOrStdout(), format, findings); err != nil {
+				return err
+			}
+			if lint.HasErrors(findings) {
+				return errLintFailed
+			}
+			return nil
+		},
+	}
+
+	addManifestFlags(cmd)
+	cmd.Flags().StringP(
+		outputFlag,
+		"o",
+		lint.FormatText,
+		fmt.Sprintf("output format, one of %s", strings.Join(lint.Formats, ", ")),
+	)
+	return cmd
+}
+
+// listClusterObjects lists the resources that components are discovered
+// from in the cluster that match the export selector.
+func listClusterObjects(cmd *cobra.Command) ([]unstructured.Unstructured, error) {
+	exportSelector, err := labels.Parse(viper.GetString(exportSelectorFlag))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", exportSelectorFlag, err)
+	}
+
+	cl, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.ListObjects(cmd.Context(), cl, namespaces(), client.MatchingLabelsSelector{Selector: exportSelector})
+}
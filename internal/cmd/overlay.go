@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+const (
+	overlayFlag          = "overlay"
+	overlayConfigMapFlag = "overlay-configmap"
+)
+
+// addOverlayFlags adds the flags for loading overlays with loadOverlays,
+// the ConfigMap flag is only added for commands that connect to a cluster.
+func addOverlayFlags(cmd *cobra.Command, configMap bool) {
+	cmd.Flags().StringSlice(
+		overlayFlag,
+		nil,
+		"files or directories containing Backstage components to merge onto the discovered components",
+	)
+	if configMap {
+		cmd.Flags().String(
+			overlayConfigMapFlag,
+			"",
+			"ConfigMap containing Backstage components to merge onto the discovered components e.g. namespace/name",
+		)
+	}
+}
+
+// loadOverlays loads the overlays from the files in paths, and from the
+// ConfigMap if it's not empty.
+//
+// If no overlays are configured, nil is returned.
+func loadOverlays(ctx context.Context, cl client.Reader, paths []string, configMap string) (*catalog.Overlays, error) {
+	if len(paths) == 0 && configMap == "" {
+		return nil, nil
+	}
+	components, err := loadOverlayComponents(ctx, cl, paths, configMap)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.NewOverlays(components), nil
+}
+
+// loadOverlayComponents loads the Components to overlay from the files in
+// paths, and from the ConfigMap if it's not empty.
+func loadOverlayComponents(ctx context.Context, cl client.Reader, paths []string, configMap string) ([]backstage.Component, error) {
+	components, err := catalog.ReadOverlays(paths)
+	if err != nil {
+		return nil, err
+	}
+	if configMap != "" {
+		namespace, name, ok := strings.Cut(configMap, "/")
+		if !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("invalid --%s %q: must be namespace/name", overlayConfigMapFlag, configMap)
+		}
+		loaded, err := catalog.LoadOverlaysConfigMap(ctx, cl, client.ObjectKey{Namespace: namespace, Name: name})
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, loaded...)
+	}
+	return components, nil
+}
@@ -1,20 +1,34 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-logr/zapr"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/config"
 	"github.com/bigkevmcd/peanut-backstage/pkg/httpapi"
+	"github.com/bigkevmcd/peanut-backstage/pkg/metrics"
 )
 
 var (
@@ -23,15 +37,26 @@ var (
 
 func init() {
 	utilruntime.Must(appsv1.AddToScheme(scheme))
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(authenticationv1.AddToScheme(scheme))
+	utilruntime.Must(authorizationv1.AddToScheme(scheme))
 	cobra.OnInitialize(initConfig)
 }
 
 const (
-	listenFlag = "listen"
-	debugFlag  = "debug"
+	listenFlag             = "listen"
+	debugFlag              = "debug"
+	exportSelectorFlag     = "export-selector"
+	changeLogSizeFlag      = "change-log-size"
+	adminListenFlag        = "admin-listen"
+	maxStalenessFlag       = "max-staleness"
+	tombstoneGraceFlag     = "tombstone-grace-period"
+	annotateTombstonesFlag = "annotate-tombstones"
 )
 
 func initConfig() {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
 }
 
@@ -39,30 +64,203 @@ func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "peanut-backstage",
 		Short: "Export Kubernetes resources as a Backstage catalog",
+		// The settings shared by the commands can only be bound to the flags
+		// of one command, so they're bound when the command is run.
+		PersistentPreRunE: bindSharedSettings,
 	}
 
 	cmd.AddCommand(newServeCmd())
+	cmd.AddCommand(newExportCmd())
+	cmd.AddCommand(newGenerateCmd())
+	cmd.AddCommand(newLintCmd())
+	cmd.AddCommand(newExplainCmd())
+	cmd.AddCommand(newDiffCmd())
+	cmd.AddCommand(newRecordCmd())
+	addKubeFlags(cmd)
 
 	return cmd
 }
 
 func newServeCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "serve",
-		Short: "Dynamic HTTP server serving Backstage components",
+		Use:           "serve",
+		Short:         "Dynamic HTTP server serving Backstage components",
+		SilenceUsage:  true,
+		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cfg, err := config.GetConfig()
-			cobra.CheckErr(err)
+			configFile := viper.GetString(configFlag)
+			if err := config.CheckRanges(viper.GetViper(), cmd.Flags()); err != nil {
+				return err
+			}
 
-			cl, err := client.New(cfg, client.Options{Scheme: scheme})
-			cobra.CheckErr(err)
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
 
-			logger := zapr.NewLogger(makeLogger(viper.GetBool(debugFlag)))
-			router := httpapi.NewRouter(logger, cl)
+			// The default cluster is only connected to if it's used, the rest
+			// config and client are nil otherwise.
+			var cfg *rest.Config
+			var cl client.Client
+			var err error
+			fixtureFile := viper.GetString(fromFixtureFlag)
+			switch {
+			case fixtureFile != "":
+				if err := checkFixtureSettings(); err != nil {
+					return err
+				}
+				cl, err = fixtureClient(fixtureFile)
+			case needsDefaultCluster():
+				cfg, err = restConfig()
+				if err == nil {
+					cl, err = client.New(cfg, client.Options{Scheme: scheme})
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			exportSelector, err := labels.Parse(viper.GetString(exportSelectorFlag))
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %w", exportSelectorFlag, err)
+			}
+			duplicates, err := duplicatePolicy()
+			if err != nil {
+				return err
+			}
+
+			level := zap.NewAtomicLevelAt(logLevel(viper.GetBool(debugFlag)))
+			logger := zapr.NewLogger(makeLogger(viper.GetBool(debugFlag), level))
+
+			// Overlays are always created so that they can be added when the
+			// configuration is reloaded.
+			overlayComponents, err := loadOverlayComponents(ctx, cl, viper.GetStringSlice(overlayFlag), viper.GetString(overlayConfigMapFlag))
+			if err != nil {
+				return err
+			}
+			overlays := catalog.NewOverlays(overlayComponents)
+
+			m := metrics.New()
+			changeLogOpts := []catalog.ChangeLogOption{catalog.WithGracePeriod(viper.GetDuration(tombstoneGraceFlag))}
+			if viper.GetBool(annotateTombstonesFlag) {
+				changeLogOpts = append(changeLogOpts, catalog.WithTombstoneAnnotation())
+			}
+			changes := catalog.NewChangeLog(viper.GetInt(changeLogSizeFlag), changeLogOpts...)
+			snapshotFile := viper.GetString(snapshotFileFlag)
+			restored := false
+			if snapshotFile != "" {
+				restored = restoreCatalog(logger, snapshotFile, changes)
+				go persistCatalog(ctx, logger, snapshotFile, changes)
+			}
+			routerOpts := []httpapi.RouterOption{}
+			var refresher *catalog.Refresher
+			var apiServerReady func(context.Context) error
+			if fixtureFile != "" {
+				refresher = replayCatalog(ctx, logger, cl, changes, m, overlays,
+					client.MatchingLabelsSelector{Selector: exportSelector})
+			} else {
+				clusters, err := discoveryClusters(cfg)
+				if err != nil {
+					return err
+				}
+				refresher, err = watchCatalog(ctx, logger, clusters, namespaces(), duplicates, changes, m, overlays,
+					client.MatchingLabelsSelector{Selector: exportSelector})
+				if err != nil {
+					return err
+				}
+				if clusters[0].name != "" {
+					clients, err := clusterClients(clusters)
+					if err != nil {
+						return err
+					}
+					routerOpts = append(routerOpts, httpapi.WithClusters(duplicates, clients...))
+				} else {
+					// Readiness only depends on the API server when there's
+					// a single cluster, so that a failing cluster doesn't
+					// take down the others.
+					apiServerReady, err = apiServerCheck(cfg)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			err = watchConfig(ctx, logger, cmd.Flags(), configFile, func(v *viper.Viper) error {
+				level.SetLevel(logLevel(v.GetBool(debugFlag)))
+				components, err := loadOverlayComponents(ctx, cl, v.GetStringSlice(overlayFlag), v.GetString(overlayConfigMapFlag))
+				if err != nil {
+					return err
+				}
+				overlays.Replace(components)
+				refresher.Trigger()
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			routerOpts = append(routerOpts,
+				httpapi.WithExportSelector(exportSelector),
+				httpapi.WithNamespaces(namespaces()...),
+				httpapi.WithChangeLog(changes),
+				httpapi.WithMetrics(m),
+				httpapi.WithMaxStaleness(viper.GetDuration(maxStalenessFlag)),
+				httpapi.WithOverlays(overlays),
+				httpapi.WithRootLocation(viper.GetString(locationNameFlag), viper.GetString(locationDescriptionFlag)),
+				httpapi.WithReadinessCheck("catalog", syncedCheck(refresher, restored)),
+			)
+			if restored {
+				routerOpts = append(routerOpts, httpapi.WithRestoredChangeLog(changes, refresher.Synced))
+			}
+			if apiServerReady != nil {
+				if restored {
+					apiServerReady = afterSync(refresher, apiServerReady)
+				}
+				routerOpts = append(routerOpts, httpapi.WithReadinessCheck("apiserver", apiServerReady))
+			}
+			authenticator, err := makeAuthenticator(ctx, cl)
+			if err != nil {
+				return err
+			}
+			if authenticator != nil {
+				routerOpts = append(routerOpts, httpapi.WithAuthenticator(authenticator))
+			}
+			authorizer, err := makeAuthorizer(cl, authenticator)
+			if err != nil {
+				return err
+			}
+			if authorizer != nil {
+				routerOpts = append(routerOpts, httpapi.WithAuthorizer(authorizer))
+			}
+
+			router := httpapi.NewRouter(logger, cl, routerOpts...)
+
+			tlsConfig, err := makeTLSConfig(ctx, logger)
+			if err != nil {
+				return err
+			}
+
+			requestsCtx, cancelRequests := context.WithCancel(context.Background())
+			defer cancelRequests()
 
 			listen := viper.GetString(listenFlag)
-			fmt.Printf("serving the root catalog at http://%s/backstage/catalog-info.yaml\n", listen)
-			return http.ListenAndServe(listen, router)
+			server := newHTTPServer(requestsCtx, listen, router)
+			server.TLSConfig = tlsConfig
+			server.RegisterOnShutdown(router.Shutdown)
+			servers := []*http.Server{server}
+			if adminListen := viper.GetString(adminListenFlag); adminListen != "" {
+				servers = append(servers, newHTTPServer(requestsCtx, adminListen, router.AdminHandler()))
+			}
+
+			protocol := "http"
+			if tlsConfig != nil {
+				protocol = "https"
+			}
+			fmt.Printf("serving the root catalog at %s://%s/backstage/catalog-info.yaml\n", protocol, listen)
+			err = runServers(ctx, logger, cancelRequests, servers...)
+			if snapshotFile != "" {
+				if err := catalog.SaveSnapshot(snapshotFile, changes); err != nil {
+					logger.Error(err, "failed to save catalog snapshot", "path", snapshotFile)
+				}
+			}
+			return err
 		},
 	}
 
@@ -76,7 +274,122 @@ func newServeCmd() *cobra.Command {
 		false,
 		"enable debug logging",
 	)
+	cmd.Flags().String(
+		exportSelectorFlag,
+		"",
+		fmt.Sprintf("only publish resources matching this label selector e.g. %s=true", backstage.ExportLabel),
+	)
+	cmd.Flags().Int(
+		changeLogSizeFlag,
+		1000,
+		"number of catalog changes to keep for the delta endpoint",
+	)
+	cmd.Flags().String(
+		tokenAuthFileFlag,
+		"",
+		"authenticate requests with the static bearer tokens in this CSV file",
+	)
+	cmd.Flags().String(
+		tokenAuthSecretFlag,
+		"",
+		"authenticate requests with the static bearer tokens in this Secret e.g. namespace/name",
+	)
+	cmd.Flags().Bool(
+		tokenReviewFlag,
+		false,
+		"authenticate bearer tokens e.g. ServiceAccount tokens with the Kubernetes TokenReview API",
+	)
+	cmd.Flags().StringSlice(
+		tokenReviewAudienceFlag,
+		nil,
+		"audiences that tokens authenticated with the TokenReview API must be valid for",
+	)
+	cmd.Flags().Bool(
+		authorizeNamespacesFlag,
+		false,
+		"only show callers components from namespaces they can list Deployments in",
+	)
+	cmd.Flags().String(
+		tlsCertFileFlag,
+		"",
+		"serve HTTPS with the certificate in this file, reloaded when it changes",
+	)
+	cmd.Flags().String(
+		tlsKeyFileFlag,
+		"",
+		"the private key for --tls-cert-file",
+	)
+	cmd.Flags().String(
+		clientCAFileFlag,
+		"",
+		"verify client certificates signed by the CA in this file and authenticate their CN or SANs",
+	)
+	cmd.Flags().Bool(
+		requireClientCertFlag,
+		false,
+		"require that clients present a certificate signed by --client-ca-file",
+	)
+	cmd.Flags().String(
+		adminListenFlag,
+		"",
+		"listen address for an unauthenticated server with the health and metrics endpoints e.g. :8081",
+	)
+	cmd.Flags().Duration(
+		maxStalenessFlag,
+		5*time.Minute,
+		"serve the last successfully listed resources for up to this long when listing fails, 0 disables stale responses",
+	)
+	cmd.Flags().Duration(
+		tombstoneGraceFlag,
+		0,
+		"keep publishing components for this long after their resources are removed e.g. during blue/green deployments",
+	)
+	cmd.Flags().Bool(
+		annotateTombstonesFlag,
+		false,
+		fmt.Sprintf("add the %s annotation to components that are kept after their resources are removed", backstage.TombstoneAnnotation),
+	)
+	cmd.Flags().String(
+		configFlag,
+		"",
+		"read settings from this YAML file, using the names of the flags as keys, and reload it when it changes",
+	)
+	cmd.Flags().String(
+		locationNameFlag,
+		httpapi.DefaultLocationName,
+		"name of the root Location",
+	)
+	cmd.Flags().String(
+		locationDescriptionFlag,
+		httpapi.DefaultLocationDescription,
+		"description of the root Location",
+	)
+	cmd.Flags().String(
+		snapshotFileFlag,
+		"",
+		"save the catalog to this file as it changes, and serve it at startup until the cluster has been synced",
+	)
+	addOverlayFlags(cmd, true)
+	addServerFlags(cmd)
+	addClusterFlags(cmd)
+	cmd.Flags().String(
+		fromFixtureFlag,
+		"",
+		"serve the catalog from a fixture file recorded with the record command, instead of a cluster",
+	)
+	cobra.CheckErr(viper.BindPFlag(fromFixtureFlag, cmd.Flags().Lookup(fromFixtureFlag)))
 	cobra.CheckErr(viper.BindPFlag(listenFlag, cmd.Flags().Lookup(listenFlag)))
+	cobra.CheckErr(viper.BindPFlag(debugFlag, cmd.Flags().Lookup(debugFlag)))
+	cobra.CheckErr(viper.BindPFlag(changeLogSizeFlag, cmd.Flags().Lookup(changeLogSizeFlag)))
+	cobra.CheckErr(viper.BindPFlag(adminListenFlag, cmd.Flags().Lookup(adminListenFlag)))
+	cobra.CheckErr(viper.BindPFlag(maxStalenessFlag, cmd.Flags().Lookup(maxStalenessFlag)))
+	cobra.CheckErr(viper.BindPFlag(snapshotFileFlag, cmd.Flags().Lookup(snapshotFileFlag)))
+	cobra.CheckErr(viper.BindPFlag(tombstoneGraceFlag, cmd.Flags().Lookup(tombstoneGraceFlag)))
+	cobra.CheckErr(viper.BindPFlag(annotateTombstonesFlag, cmd.Flags().Lookup(annotateTombstonesFlag)))
+	for _, flag := range []string{tokenAuthFileFlag, tokenAuthSecretFlag, tokenReviewFlag, tokenReviewAudienceFlag, authorizeNamespacesFlag,
+		tlsCertFileFlag, tlsKeyFileFlag, clientCAFileFlag, requireClientCertFlag} {
+		cobra.CheckErr(viper.BindPFlag(flag, cmd.Flags().Lookup(flag)))
+	}
 	return cmd
 }
 
@@ -85,14 +398,15 @@ func Execute() {
 	cobra.CheckErr(newRootCmd().Execute())
 }
 
-func makeLogger(debug bool) *zap.Logger {
-	var zapLog *zap.Logger
-	var err error
+// makeLogger creates a logger that logs at the level, the level can be
+// changed while the logger is in use.
+func makeLogger(debug bool, level zap.AtomicLevel) *zap.Logger {
+	cfg := zap.NewProductionConfig()
 	if debug {
-		zapLog, err = zap.NewDevelopment()
-	} else {
-		zapLog, err = zap.NewProduction()
+		cfg = zap.NewDevelopmentConfig()
 	}
+	cfg.Level = level
+	zapLog, err := cfg.Build()
 	cobra.CheckErr(err)
 	return zapLog
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	readTimeoutFlag       = "read-timeout"
+	readHeaderTimeoutFlag = "read-header-timeout"
+	writeTimeoutFlag      = "write-timeout"
+	idleTimeoutFlag       = "idle-timeout"
+	shutdownGraceFlag     = "shutdown-grace-period"
+)
+
+func addServerFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration(
+		readTimeoutFlag,
+		30*time.Second,
+		"maximum duration for reading an entire request",
+	)
+	cmd.Flags().Duration(
+		readHeaderTimeoutFlag,
+		10*time.Second,
+		"maximum duration for reading request headers",
+	)
+	cmd.Flags().Duration(
+		writeTimeoutFlag,
+		0,
+		"maximum duration for writing a response, this applies to the events stream, 0 means no timeout",
+	)
+	cmd.Flags().Duration(
+		idleTimeoutFlag,
+		2*time.Minute,
+		"maximum duration to keep idle keep-alive connections open",
+	)
+	cmd.Flags().Duration(
+		shutdownGraceFlag,
+		20*time.Second,
+		"maximum duration to wait for in-flight requests to complete when shutting down",
+	)
+	for _, flag := range []string{readTimeoutFlag, readHeaderTimeoutFlag, writeTimeoutFlag, idleTimeoutFlag, shutdownGraceFlag} {
+		cobra.CheckErr(viper.BindPFlag(flag, cmd.Flags().Lookup(flag)))
+	}
+}
+
+// newHTTPServer creates an http.Server with the configured timeouts.
+//
+// Requests are served with contexts derived from baseCtx, so cancelling it
+// cancels in-flight requests, including their calls to the Kubernetes API.
+func newHTTPServer(baseCtx context.Context, addr string, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadTimeout:       viper.GetDuration(readTimeoutFlag),
+		ReadHeaderTimeout: viper.GetDuration(readHeaderTimeoutFlag),
+		WriteTimeout:      viper.GetDuration(writeTimeoutFlag),
+		IdleTimeout:       viper.GetDuration(idleTimeoutFlag),
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+}
+
+// runServers runs the servers until the context is cancelled, and then
+// shuts them down gracefully.
+//
+// In-flight requests have the shutdown grace period to complete, after that
+// cancelRequests is called and the remaining connections are closed.
+func runServers(ctx context.Context, logger logr.Logger, cancelRequests context.CancelFunc, servers ...*http.Server) error {
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		go func() {
+			var err error
+			if s.TLSConfig != nil {
+				err = s.ListenAndServeTLS("", "")
+			} else {
+				err = s.ListenAndServe()
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("failed to serve on %s: %w", s.Addr, err)
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), viper.GetDuration(shutdownGraceFlag))
+	defer cancel()
+	for _, s := range servers {
+		if shutdownErr := s.Shutdown(shutdownCtx); shutdownErr != nil {
+			logger.Error(shutdownErr, "failed to shut down gracefully", "addr", s.Addr)
+			cancelRequests()
+			s.Close()
+		}
+	}
+	cancelRequests()
+
+	return err
+}
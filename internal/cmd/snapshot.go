@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/go-logr/logr"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+const snapshotFileFlag = "snapshot-file"
+
+// restoreCatalog loads the ChangeLog from the snapshot file, returning true
+// if it was restored.
+//
+// A missing or unreadable snapshot is not fatal, the catalog will be
+// populated when the cache syncs.
+func restoreCatalog(logger logr.Logger, path string, changes *catalog.ChangeLog) bool {
+	if err := catalog.LoadSnapshot(path, changes); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Error(err, "failed to restore catalog snapshot", "path", path)
+		}
+		return false
+	}
+	_, revision := changes.Snapshot()
+	logger.Info("restored catalog snapshot", "path", path, "revision", revision)
+	return true
+}
+
+// persistCatalog writes a snapshot of the ChangeLog to the file each time
+// that it changes, until the context is cancelled.
+func persistCatalog(ctx context.Context, logger logr.Logger, path string, changes *catalog.ChangeLog) {
+	updated, cancel := changes.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-updated:
+			if err := catalog.SaveSnapshot(path, changes); err != nil {
+				logger.Error(err, "failed to save catalog snapshot", "path", path)
+			}
+		}
+	}
+}
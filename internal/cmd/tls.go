@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/viper"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/tlsconfig"
+)
+
+const (
+	tlsCertFileFlag       = "tls-cert-file"
+	tlsKeyFileFlag        = "tls-key-file"
+	clientCAFileFlag      = "client-ca-file"
+	requireClientCertFlag = "require-client-cert"
+
+	// certificateReloadInterval is how often the certificate files are
+	// checked for changes.
+	certificateReloadInterval = 10 * time.Second
+)
+
+// makeTLSConfig creates a tls.Config from the configured options, the
+// certificates are reloaded when they change until the context is
+// cancelled.
+//
+// If TLS is not configured, nil is returned.
+func makeTLSConfig(ctx context.Context, logger logr.Logger) (*tls.Config, error) {
+	certFile, keyFile := viper.GetString(tlsCertFileFlag), viper.GetString(tlsKeyFileFlag)
+	clientCAFile := viper.GetString(clientCAFileFlag)
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, fmt.Errorf("--%s requires --%s and --%s", clientCAFileFlag, tlsCertFileFlag, tlsKeyFileFlag)
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both --%s and --%s must be provided", tlsCertFileFlag, tlsKeyFileFlag)
+	}
+
+	reloader, err := tlsconfig.NewReloader(logger.WithName("tls"), certFile, keyFile, clientCAFile, viper.GetBool(requireClientCertFlag))
+	if err != nil {
+		return nil, err
+	}
+	go reloader.Start(ctx, certificateReloadInterval)
+
+	return reloader.Config(), nil
+}
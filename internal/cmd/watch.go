@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/metrics"
+)
+
+// watchCatalog starts a cache for each cluster watching the resources that
+// components are discovered from, and keeps the ChangeLog up to date as they
+// change.
+//
+// If namespaces are provided, only resources in those namespaces are cached.
+//
+// This doesn't block, the returned Refresher reports when a cache has synced
+// and the initial catalog has been recorded. Clusters whose caches haven't
+// synced are treated as unavailable, so that a cluster that can't be reached
+// doesn't prevent the others from being published.
+func watchCatalog(ctx context.Context, logger logr.Logger, clusters []kubeCluster, namespaces []string, duplicates catalog.DuplicatePolicy, changes *catalog.ChangeLog, m *metrics.Metrics, overlays *catalog.Overlays, opts ...client.ListOption) (*catalog.Refresher, error) {
+	// The mapper is static so that caches can be created for clusters that
+	// can't be reached yet.
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	cacheOpts := cache.Options{Scheme: scheme, Mapper: mapper}
+	if len(namespaces) > 0 {
+		cacheOpts.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range namespaces {
+			cacheOpts.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+
+	caches := make([]cache.Cache, len(clusters))
+	readers := make([]*syncedReader, len(clusters))
+	discovered := make([]catalog.Cluster, len(clusters))
+	for i, v := range clusters {
+		c, err := cache.New(v.config, cacheOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+		caches[i] = c
+		readers[i] = &syncedReader{Reader: c, cluster: v.name}
+		discovered[i] = catalog.Cluster{Name: v.name, Client: readers[i]}
+	}
+
+	refresher := catalog.NewClusterRefresher(logger.WithName("refresher"), discovered, duplicates, changes, opts...)
+	refresher.Metrics = m
+	refresher.Overlays = overlays
+
+	var started atomic.Bool
+	for i, c := range caches {
+		informer, err := c.GetInformer(ctx, &appsv1.Deployment{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get informer for deployments: %w", err)
+		}
+		if _, err := informer.AddEventHandler(refresher.EventHandler()); err != nil {
+			return nil, fmt.Errorf("failed to add event handler: %w", err)
+		}
+
+		cacheLogger := logger
+		if name := clusters[i].name; name != "" {
+			cacheLogger = logger.WithValues("cluster", name)
+		}
+		go func() {
+			if err := c.Start(ctx); err != nil {
+				cacheLogger.Error(err, "failed to start cache")
+			}
+		}()
+		go func() {
+			if !c.WaitForCacheSync(ctx) {
+				cacheLogger.Error(errors.New("failed to sync cache"), "cache not synced")
+				return
+			}
+			readers[i].synced.Store(true)
+			if started.CompareAndSwap(false, true) {
+				_ = refresher.Start(ctx)
+				return
+			}
+			refresher.Trigger()
+		}()
+	}
+
+	return refresher, nil
+}
+
+// syncedReader fails to read from the cache until it has synced, rather than
+// blocking.
+type syncedReader struct {
+	client.Reader
+	cluster string
+	synced  atomic.Bool
+}
+
+func (r *syncedReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if !r.synced.Load() {
+		return r.notSynced()
+	}
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+func (r *syncedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if !r.synced.Load() {
+		return r.notSynced()
+	}
+	return r.Reader.List(ctx, list, opts...)
+}
+
+func (r *syncedReader) notSynced() error {
+	return fmt.Errorf("the cache for cluster %q has not synced", r.cluster)
+}
+
+// syncedCheck is a readiness check that passes once the Refresher has
+// recorded the catalog, or immediately if the catalog was restored from a
+// snapshot, as the catalog is served from the snapshot until it's synced.
+func syncedCheck(r *catalog.Refresher, restored bool) func(context.Context) error {
+	return func(context.Context) error {
+		if !restored && !r.Synced() {
+			return errors.New("catalog has not been synced")
+		}
+		return nil
+	}
+}
+
+// afterSync wraps a readiness check so that it only applies once the
+// Refresher has recorded the catalog, while a restored catalog is served the
+// server is ready even if the cluster can't be reached.
+func afterSync(r *catalog.Refresher, check func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if !r.Synced() {
+			return nil
+		}
+		return check(ctx)
+	}
+}
+
+// apiServerCheckTTL is how long the result of checking the API server is
+// reused, /readyz doesn't require authentication so it could otherwise be
+// used to make requests to the API server at any rate.
+const apiServerCheckTTL = 5 * time.Second
+
+// apiServerCheck is a readiness check that passes if the API server is
+// reachable.
+func apiServerCheck(cfg *rest.Config) (func(context.Context) error, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+
+	return cachedCheck(apiServerCheckTTL, func(ctx context.Context) error {
+		return dc.RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+	}), nil
+}
+
+// cachedCheck wraps a readiness check, reusing its result for the TTL.
+//
+// Concurrent calls wait for the check in progress rather than starting
+// another, and results are not reused if the context was cancelled.
+func cachedCheck(ttl time.Duration, check func(context.Context) error) func(context.Context) error {
+	var mu sync.Mutex
+	var checked time.Time
+	var result error
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return result
+		}
+		err := check(ctx)
+		if ctx.Err() == nil {
+			checked, result = time.Now(), err
+		}
+		return err
+	}
+}
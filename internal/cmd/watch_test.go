@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+func TestCachedCheck(t *testing.T) {
+	checkTests := []struct {
+		name      string
+		ttl       time.Duration
+		err       error
+		wantCalls int
+	}{
+		{name: "passing check within the TTL", ttl: time.Hour, wantCalls: 1},
+		{name: "failing check within the TTL", ttl: time.Hour, err: errors.New("unreachable"), wantCalls: 1},
+		{name: "expired", ttl: 0, wantCalls: 3},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			check := cachedCheck(tt.ttl, func(context.Context) error {
+				calls++
+				return tt.err
+			})
+
+			for range 3 {
+				if err := check(context.Background()); err != tt.err {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedCheckCancelled(t *testing.T) {
+	calls := 0
+	check := cachedCheck(time.Hour, func(ctx context.Context) error {
+		calls++
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := check(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v", err)
+	}
+	if err := check(context.Background()); err != nil {
+		t.Fatalf("cancelled result was reused: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestReadinessWithRestoredCatalog(t *testing.T) {
+	r := catalog.NewRefresher(logr.Discard(), nil, catalog.NewChangeLog(10))
+	unreachable := func(context.Context) error {
+		return errors.New("unreachable")
+	}
+
+	if err := syncedCheck(r, false)(context.Background()); err == nil {
+		t.Error("unsynced catalog was ready")
+	}
+	if err := syncedCheck(r, true)(context.Background()); err != nil {
+		t.Errorf("restored catalog was not ready: %v", err)
+	}
+	if err := afterSync(r, unreachable)(context.Background()); err != nil {
+		t.Errorf("check was applied before the catalog synced: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// User is the identity of an authenticated caller.
+type User struct {
+	Name   string
+	UID    string
+	Groups []string
+}
+
+// Authenticator authenticates HTTP requests.
+type Authenticator interface {
+	// Authenticate returns the User making the request.
+	//
+	// If the request doesn't have credentials that this Authenticator
+	// understands, or the credentials are not valid, false is returned.
+	Authenticate(r *http.Request) (*User, bool, error)
+}
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions as
+// Authenticators.
+type AuthenticatorFunc func(r *http.Request) (*User, bool, error)
+
+// Authenticate calls f(r).
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*User, bool, error) {
+	return f(r)
+}
+
+// Union returns an Authenticator that tries each of the Authenticators in
+// turn, and returns the first User that is authenticated.
+//
+// Errors are only returned if none of the Authenticators authenticate the
+// request.
+func Union(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (*User, bool, error) {
+		var errs []error
+		for _, a := range authenticators {
+			u, ok, err := a.Authenticate(r)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if ok {
+				return u, true, nil
+			}
+		}
+		return nil, false, errors.Join(errs...)
+	})
+}
+
+// BearerToken returns the bearer token from the Authorization header of the
+// request.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of the context with the User.
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFrom returns the User from the context if there is one.
+func UserFrom(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(userKey{}).(*User)
+	return u, ok
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Authorizer decides whether a User can see the resources in a namespace.
+type Authorizer interface {
+	Authorized(ctx context.Context, u *User, namespace string) (bool, error)
+}
+
+// SubjectAccessReview authorizes Users by submitting a SubjectAccessReview
+// to the Kubernetes API, checking whether the User can list Deployments in
+// the namespace.
+type SubjectAccessReview struct {
+	client client.Client
+}
+
+// NewSubjectAccessReview creates and returns a new SubjectAccessReview
+// authorizer.
+func NewSubjectAccessReview(c client.Client) *SubjectAccessReview {
+	return &SubjectAccessReview{client: c}
+}
+
+// Authorized implements the Authorizer interface.
+func (s *SubjectAccessReview) Authorized(ctx context.Context, u *User, namespace string) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   u.Name,
+			UID:    u.UID,
+			Groups: u.Groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Group:     "apps",
+				Resource:  "deployments",
+			},
+		},
+	}
+	if err := s.client.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("failed to create SubjectAccessReview: %w", err)
+	}
+
+	return review.Status.Allowed, nil
+}
+
+// CachedAuthorizer caches the decisions of an Authorizer for each User and
+// namespace for a TTL, so that frequent requests from the same User don't
+// each need new decisions.
+//
+// Errors are not cached.
+type CachedAuthorizer struct {
+	authorizer Authorizer
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	decisions map[decisionKey]decision
+	swept     time.Time
+}
+
+type decisionKey struct {
+	name      string
+	uid       string
+	groups    string
+	namespace string
+}
+
+type decision struct {
+	allowed bool
+	expires time.Time
+}
+
+// NewCachedAuthorizer creates and returns a new CachedAuthorizer that caches
+// the decisions of the Authorizer for the TTL.
+func NewCachedAuthorizer(az Authorizer, ttl time.Duration) *CachedAuthorizer {
+	return &CachedAuthorizer{
+		authorizer: az,
+		ttl:        ttl,
+		now:        time.Now,
+		decisions:  map[decisionKey]decision{},
+	}
+}
+
+// Authorized implements the Authorizer interface.
+func (c *CachedAuthorizer) Authorized(ctx context.Context, u *User, namespace string) (bool, error) {
+	key := decisionKey{
+		name:      u.Name,
+		uid:       u.UID,
+		groups:    strings.Join(slices.Sorted(slices.Values(u.Groups)), "\x00"),
+		namespace: namespace,
+	}
+	c.mu.Lock()
+	d, ok := c.decisions[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(d.expires) {
+		return d.allowed, nil
+	}
+
+	allowed, err := c.authorizer.Authorized(ctx, u, namespace)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	// Expired decisions are removed periodically, so that the cache doesn't
+	// grow with Users that have stopped making requests.
+	if now.Sub(c.swept) >= c.ttl {
+		for k, v := range c.decisions {
+			if !now.Before(v.expires) {
+				delete(c.decisions, k)
+			}
+		}
+		c.swept = now
+	}
+	c.decisions[key] = decision{allowed: allowed, expires: now.Add(c.ttl)}
+	return allowed, nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestSubjectAccessReviewAuthorized(t *testing.T) {
+	fc := newSubjectAccessReviewClient(t, map[string][]string{
+		"alice": {"team-a"},
+	})
+	az := NewSubjectAccessReview(fc)
+
+	authzTests := []struct {
+		user      string
+		namespace string
+		want      bool
+	}{
+		{"alice", "team-a", true},
+		{"alice", "team-b", false},
+		{"bob", "team-a", false},
+	}
+
+	for _, tt := range authzTests {
+		t.Run(tt.user+"/"+tt.namespace, func(t *testing.T) {
+			allowed, err := az.Authorized(context.TODO(), &User{Name: tt.user}, tt.namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed != tt.want {
+				t.Fatalf("got %v, want %v", allowed, tt.want)
+			}
+		})
+	}
+}
+
+// newSubjectAccessReviewClient returns a fake client that allows users to
+// list Deployments in the namespaces in the map.
+func newSubjectAccessReviewClient(t *testing.T, namespaces map[string][]string) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				attrs := review.Spec.ResourceAttributes
+				review.Status.Allowed = attrs.Verb == "list" && attrs.Group == "apps" && attrs.Resource == "deployments" &&
+					slices.Contains(namespaces[review.Spec.User], attrs.Namespace)
+				return nil
+			},
+		}).
+		Build()
+}
+
+func TestCachedAuthorizer(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	az := &countingAuthorizer{allowed: map[string]bool{"alice/team-a": true}}
+	cached := NewCachedAuthorizer(az, 10*time.Second)
+	cached.now = func() time.Time { return now }
+	alice := &User{Name: "alice", Groups: []string{"devs", "ops"}}
+
+	assertAuthorized := func(u *User, namespace string, want bool, wantCalls int) {
+		t.Helper()
+		allowed, err := cached.Authorized(context.TODO(), u, namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != want {
+			t.Errorf("got %v for %s/%s, want %v", allowed, u.Name, namespace, want)
+		}
+		if az.calls != wantCalls {
+			t.Errorf("got %d calls, want %d", az.calls, wantCalls)
+		}
+	}
+
+	assertAuthorized(alice, "team-a", true, 1)
+	assertAuthorized(alice, "team-a", true, 1)
+	assertAuthorized(&User{Name: "alice", Groups: []string{"ops", "devs"}}, "team-a", true, 1)
+	assertAuthorized(alice, "team-b", false, 2)
+	assertAuthorized(alice, "team-b", false, 2)
+	// Different groups can have different permissions.
+	assertAuthorized(&User{Name: "alice", Groups: []string{"devs"}}, "team-a", true, 3)
+	assertAuthorized(&User{Name: "bob", Groups: []string{"devs", "ops"}}, "team-a", false, 4)
+
+	now = now.Add(10 * time.Second)
+	assertAuthorized(alice, "team-a", true, 5)
+	if len(cached.decisions) != 1 {
+		t.Errorf("got %d cached decisions, want 1", len(cached.decisions))
+	}
+}
+
+func TestCachedAuthorizerDoesNotCacheErrors(t *testing.T) {
+	az := &countingAuthorizer{err: errors.New("unavailable")}
+	cached := NewCachedAuthorizer(az, time.Minute)
+
+	for range 2 {
+		if _, err := cached.Authorized(context.TODO(), &User{Name: "alice"}, "team-a"); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if az.calls != 2 {
+		t.Errorf("got %d calls, want 2", az.calls)
+	}
+}
+
+type countingAuthorizer struct {
+	allowed map[string]bool
+	err     error
+	calls   int
+}
+
+func (c *countingAuthorizer) Authorized(ctx context.Context, u *User, namespace string) (bool, error) {
+	c.calls++
+	return c.allowed[u.Name+"/"+namespace], c.err
+}
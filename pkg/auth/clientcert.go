@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// ClientCertificate authenticates requests with verified TLS client
+// certificates.
+//
+// The User's name is the Common Name of the certificate, falling back to
+// the first DNS, URI or email Subject Alternative Name, and the
+// Organizations are the User's groups.
+type ClientCertificate struct{}
+
+// Authenticate implements the Authenticator interface.
+func (ClientCertificate) Authenticate(r *http.Request) (*User, bool, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false, nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	name := cert.Subject.CommonName
+	switch {
+	case name != "":
+	case len(cert.DNSNames) > 0:
+		name = cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		name = cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		name = cert.EmailAddresses[0]
+	default:
+		return nil, false, nil
+	}
+
+	return &User{Name: name, Groups: cert.Subject.Organization}, true, nil
+}
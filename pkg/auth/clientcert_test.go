@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClientCertificateAuthenticate(t *testing.T) {
+	spiffe, err := url.Parse("spiffe://cluster.local/ns/backstage/sa/backstage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certTests := []struct {
+		name   string
+		state  *tls.ConnectionState
+		want   *User
+		wantOK bool
+	}{
+		{"no TLS", nil, nil, false},
+		{"no verified certificates", &tls.ConnectionState{}, nil, false},
+		{
+			"common name",
+			verifiedState(&x509.Certificate{Subject: pkix.Name{CommonName: "backstage", Organization: []string{"catalog-readers"}}}),
+			&User{Name: "backstage", Groups: []string{"catalog-readers"}},
+			true,
+		},
+		{
+			"DNS name",
+			verifiedState(&x509.Certificate{DNSNames: []string{"backstage.example.com"}}),
+			&User{Name: "backstage.example.com"},
+			true,
+		},
+		{
+			"URI",
+			verifiedState(&x509.Certificate{URIs: []*url.URL{spiffe}}),
+			&User{Name: "spiffe://cluster.local/ns/backstage/sa/backstage"},
+			true,
+		},
+		{"no identity", verifiedState(&x509.Certificate{}), nil, false},
+	}
+
+	for _, tt := range certTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest(t, "")
+			r.TLS = tt.state
+			u, ok, err := ClientCertificate{}.Authenticate(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.want, u); diff != "" {
+				t.Errorf("failed to authenticate:\n%s", diff)
+			}
+		})
+	}
+}
+
+func verifiedState(cert *x509.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+}
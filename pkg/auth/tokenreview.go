@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TokenReview authenticates bearer tokens, e.g. ServiceAccount tokens, by
+// submitting a TokenReview to the Kubernetes API.
+type TokenReview struct {
+	client    client.Client
+	audiences []string
+}
+
+// NewTokenReview creates and returns a new TokenReview authenticator.
+//
+// If audiences are provided, the token must be valid for at least one of
+// them.
+func NewTokenReview(c client.Client, audiences ...string) *TokenReview {
+	return &TokenReview{client: c, audiences: audiences}
+}
+
+// Authenticate implements the Authenticator interface.
+func (t *TokenReview) Authenticate(r *http.Request) (*User, bool, error) {
+	token, ok := BearerToken(r)
+	if !ok {
+		return nil, false, nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: t.audiences,
+		},
+	}
+	if err := t.client.Create(r.Context(), review); err != nil {
+		return nil, false, fmt.Errorf("failed to create TokenReview: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, false, nil
+	}
+
+	return &User{
+		Name:   review.Status.User.Username,
+		UID:    review.Status.User.UID,
+		Groups: review.Status.User.Groups,
+	}, true, nil
+}
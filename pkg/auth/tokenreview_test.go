@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestTokenReviewAuthenticate(t *testing.T) {
+	fc := newTokenReviewClient(t, map[string]authenticationv1.UserInfo{
+		"valid-token": {
+			Username: "system:serviceaccount:backstage:backstage",
+			UID:      "1234",
+			Groups:   []string{"system:serviceaccounts"},
+		},
+	})
+	tr := NewTokenReview(fc, "peanut-backstage")
+
+	u, ok, err := tr.Authenticate(newRequest(t, "Bearer valid-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("failed to authenticate valid token")
+	}
+	want := &User{
+		Name:   "system:serviceaccount:backstage:backstage",
+		UID:    "1234",
+		Groups: []string{"system:serviceaccounts"},
+	}
+	if diff := cmp.Diff(want, u); diff != "" {
+		t.Fatalf("failed to authenticate:\n%s", diff)
+	}
+
+	_, ok, err = tr.Authenticate(newRequest(t, "Bearer invalid-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("authenticated an invalid token")
+	}
+}
+
+func TestUnion(t *testing.T) {
+	fc := newTokenReviewClient(t, map[string]authenticationv1.UserInfo{
+		"sa-token": {Username: "system:serviceaccount:backstage:backstage"},
+	})
+	tokens, err := ParseStaticTokens(strings.NewReader("static-token,alice,1001\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Union(tokens, NewTokenReview(fc))
+
+	for token, want := range map[string]string{
+		"static-token": "alice",
+		"sa-token":     "system:serviceaccount:backstage:backstage",
+	} {
+		u, ok, err := a.Authenticate(newRequest(t, "Bearer "+token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || u.Name != want {
+			t.Errorf("got %v, want %s", u, want)
+		}
+	}
+}
+
+// newTokenReviewClient returns a fake client that authenticates the tokens
+// in the map when TokenReviews are created.
+func newTokenReviewClient(t *testing.T, users map[string]authenticationv1.UserInfo) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := authenticationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authenticationv1.TokenReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				if u, ok := users[review.Spec.Token]; ok {
+					review.Status.Authenticated = true
+					review.Status.User = u
+				}
+				return nil
+			},
+		}).
+		Build()
+}
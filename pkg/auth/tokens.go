@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TokensSecretKey is the key in a Secret that static tokens are loaded
+// from.
+const TokensSecretKey = "tokens.csv"
+
+// StaticTokens authenticates requests with a fixed set of bearer tokens.
+type StaticTokens struct {
+	tokens map[string]*User
+}
+
+// ParseStaticTokens parses static tokens from CSV.
+//
+// The format is the same as the Kubernetes API server's static token file,
+// each line is "token,user,uid" followed by an optional quoted
+// comma-separated list of groups.
+func ParseStaticTokens(r io.Reader) (*StaticTokens, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	tokens := map[string]*User{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse static tokens: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 3 {
+			return nil, fmt.Errorf("invalid static token on line %d: want at least 3 fields, got %d", line, len(record))
+		}
+		token := strings.TrimSpace(record[0])
+		if token == "" {
+			return nil, fmt.Errorf("invalid static token on line %d: empty token", line)
+		}
+		if _, ok := tokens[token]; ok {
+			return nil, fmt.Errorf("invalid static token on line %d: duplicate token", line)
+		}
+		u := &User{Name: record[1], UID: record[2]}
+		if len(record) > 3 {
+			for _, g := range strings.Split(record[3], ",") {
+				if g = strings.TrimSpace(g); g != "" {
+					u.Groups = append(u.Groups, g)
+				}
+			}
+		}
+		tokens[token] = u
+	}
+
+	return &StaticTokens{tokens: tokens}, nil
+}
+
+// LoadStaticTokensFile loads static tokens from a CSV file.
+func LoadStaticTokensFile(filename string) (*StaticTokens, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open static tokens: %w", err)
+	}
+	defer f.Close()
+
+	return ParseStaticTokens(f)
+}
+
+// LoadStaticTokensSecret loads static tokens from the TokensSecretKey in a
+// Secret.
+func LoadStaticTokensSecret(ctx context.Context, c client.Reader, key client.ObjectKey) (*StaticTokens, error) {
+	var secret corev1.Secret
+	if err := c.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("failed to load static tokens from Secret %s: %w", key, err)
+	}
+	data, ok := secret.Data[TokensSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("no %s key in Secret %s", TokensSecretKey, key)
+	}
+
+	return ParseStaticTokens(strings.NewReader(string(data)))
+}
+
+// Authenticate implements the Authenticator interface.
+func (s *StaticTokens) Authenticate(r *http.Request) (*User, bool, error) {
+	token, ok := BearerToken(r)
+	if !ok {
+		return nil, false, nil
+	}
+	for k, u := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(token)) == 1 {
+			return u, true, nil
+		}
+	}
+	return nil, false, nil
+}
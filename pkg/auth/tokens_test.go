@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testTokens = `# static tokens
+token-1,alice,1001,"admins,developers"
+token-2,bob,1002
+`
+
+func TestStaticTokensAuthenticate(t *testing.T) {
+	tokens, err := ParseStaticTokens(strings.NewReader(testTokens))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authTests := []struct {
+		name          string
+		authorization string
+		want          *User
+		wantOK        bool
+	}{
+		{"no header", "", nil, false},
+		{"basic auth", "Basic dGVzdDp0ZXN0", nil, false},
+		{"unknown token", "Bearer token-3", nil, false},
+		{"token with groups", "Bearer token-1", &User{Name: "alice", UID: "1001", Groups: []string{"admins", "developers"}}, true},
+		{"token without groups", "bearer token-2", &User{Name: "bob", UID: "1002"}, true},
+	}
+
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, ok, err := tokens.Authenticate(newRequest(t, tt.authorization))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.want, u); diff != "" {
+				t.Errorf("failed to authenticate:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseStaticTokensErrors(t *testing.T) {
+	parseTests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"too few fields", "token-1,alice\n", "invalid static token on line 1: want at least 3 fields, got 2"},
+		{"empty token", ",alice,1001\n", "invalid static token on line 1: empty token"},
+		{"duplicate token", "token-1,alice,1001\ntoken-1,bob,1002\n", "invalid static token on line 2: duplicate token"},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseStaticTokens(strings.NewReader(tt.data))
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadStaticTokensSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tokens", Namespace: "test-ns"},
+		Data: map[string][]byte{
+			TokensSecretKey: []byte(testTokens),
+		},
+	}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	fc := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	tokens, err := LoadStaticTokensSecret(context.TODO(), fc, client.ObjectKeyFromObject(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, ok, err := tokens.Authenticate(newRequest(t, "Bearer token-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || u.Name != "bob" {
+		t.Fatalf("failed to authenticate with token from Secret, got %v", u)
+	}
+}
+
+func newRequest(t *testing.T, authorization string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/backstage/catalog-info.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
@@ -22,32 +22,34 @@ const (
 
 // Component is a representation of a Backstage Location.
 type Component struct {
-	APIVersion string            `yaml:"apiVersion"`
-	Kind       string            `yaml:"kind"`
-	Metadata   BackstageMetadata `yaml:"metadata"`
-	Spec       ComponentSpec     `yaml:"spec,omitempty"`
+	APIVersion string            `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string            `json:"kind" yaml:"kind"`
+	Metadata   BackstageMetadata `json:"metadata" yaml:"metadata"`
+	Spec       ComponentSpec     `json:"spec,omitempty" yaml:"spec,omitempty"`
 }
 
 // ComponentSpec
 type ComponentSpec struct {
-	Type      string `yaml:"type"`
-	Lifecycle string `yaml:"lifecycle"`
-	Owner     string `yaml:"owner"`
-	System    string `yaml:"system"`
+	Type      string `json:"type" yaml:"type"`
+	Lifecycle string `json:"lifecycle" yaml:"lifecycle"`
+	Owner     string `json:"owner" yaml:"owner"`
+	System    string `json:"system" yaml:"system"`
 }
 
 // ComponentParser parses the labels and annotations on runtime Objects and
 // extracts components from the labels and annotations.
 type ComponentParser struct {
-	Accessor   meta.MetadataAccessor
-	components map[string]discoveryComponent
+	Accessor     meta.MetadataAccessor
+	components   map[string]discoveryComponent
+	explanations map[string]*Explanation
 }
 
 // NewComponentParser creates and returns a new ComponentParser ready for use.
 func NewComponentParser() *ComponentParser {
 	return &ComponentParser{
-		Accessor:   meta.NewAccessor(),
-		components: make(map[string]discoveryComponent),
+		Accessor:     meta.NewAccessor(),
+		components:   make(map[string]discoveryComponent),
+		explanations: make(map[string]*Explanation),
 	}
 }
 
@@ -117,6 +119,7 @@ func (p *ComponentParser) Add(list runtime.Object) error {
 		}
 		c.links = links
 
+		p.explain(obj, labels, annotations, c)
 		p.components[componentName] = c
 
 		return nil
@@ -124,10 +127,11 @@ func (p *ComponentParser) Add(list runtime.Object) error {
 }
 
 // Components returns the Components that were discovered during the parsing
-// process.
+// process, sorted by name.
 func (p *ComponentParser) Components() []Component {
 	result := []Component{}
-	for _, v := range p.components {
+	for _, k := range slices.Sorted(maps.Keys(p.components)) {
+		v := p.components[k]
 		result = append(result, Component{
 			APIVersion: APIVersion,
 			Kind:       KindComponent,
@@ -0,0 +1,48 @@
+package backstage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeComponents reads the YAML documents from r e.g. a catalog-info.yaml
+// file, and decodes the Components.
+//
+// Entities of other kinds e.g. Locations or Systems are skipped, as are
+// empty documents, and fields that aren't supported by Component are
+// ignored.
+func DecodeComponents(r io.Reader) ([]Component, error) {
+	decoder := yaml.NewDecoder(r)
+	result := []Component{}
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return nil, fmt.Errorf("failed to decode entity: %w", err)
+		}
+		if len(node.Content) == 0 || node.Content[0].Kind == yaml.ScalarNode && node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		var header struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := node.Decode(&header); err != nil {
+			return nil, fmt.Errorf("failed to decode entity: %w", err)
+		}
+		if header.Kind != KindComponent {
+			continue
+		}
+
+		var c Component
+		if err := node.Decode(&c); err != nil {
+			return nil, fmt.Errorf("failed to decode component: %w", err)
+		}
+		result = append(result, c)
+	}
+}
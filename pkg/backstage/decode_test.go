@@ -0,0 +1,74 @@
+package backstage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeComponents(t *testing.T) {
+	catalogInfo := `apiVersion: backstage.io/v1alpha1
+kind: Location
+metadata:
+  name: example
+spec:
+  targets:
+    - ./component.yaml
+---
+---
+apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: nginx
+  description: Web server
+  annotations:
+    backstage.io/techdocs-ref: dir:.
+  tags:
+    - web
+  links:
+    - url: https://example.com/
+      title: Dashboard
+      icon: dashboard
+spec:
+  type: web-server
+  lifecycle: production
+  owner: group:team-a
+  dependsOn:
+    - component:mysql
+`
+
+	components, err := DecodeComponents(strings.NewReader(catalogInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Component{
+		{
+			APIVersion: APIVersion,
+			Kind:       KindComponent,
+			Metadata: BackstageMetadata{
+				Name:        "nginx",
+				Description: "Web server",
+				Annotations: map[string]string{"backstage.io/techdocs-ref": "dir:."},
+				Tags:        []string{"web"},
+				Links:       []Link{{URL: "https://example.com/", Title: "Dashboard", Icon: "dashboard"}},
+			},
+			Spec: ComponentSpec{
+				Type:      "web-server",
+				Lifecycle: "production",
+				Owner:     "group:team-a",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, components); diff != "" {
+		t.Fatalf("failed to decode components:\n%s", diff)
+	}
+}
+
+func TestDecodeComponentsErrors(t *testing.T) {
+	_, err := DecodeComponents(strings.NewReader("kind: Component\nmetadata: [test]\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to decode component:") {
+		t.Fatalf("got error %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package backstage
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Explanation describes how a Component was derived from resources.
+type Explanation struct {
+	Component string `json:"component"`
+	// Objects are the resources that contributed to the Component, in the
+	// order that they were parsed.
+	Objects []ObjectReference `json:"objects"`
+	// Fields are the fields of the Component, and where their values came
+	// from.
+	Fields []FieldSource `json:"fields"`
+	// Overrides are the values that were replaced by a later resource.
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// FieldSource records where the value of a field in a Component came from.
+type FieldSource struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// Object is the resource the value came from, this is nil for values
+	// that don't come from a resource.
+	Object *ObjectReference `json:"object,omitempty"`
+}
+
+// Override records a value for a field that was replaced when a later
+// resource for the same Component was parsed.
+type Override struct {
+	Field    string      `json:"field"`
+	Previous FieldSource `json:"previous"`
+	Current  FieldSource `json:"current"`
+}
+
+// Explain returns the explanation for how the named Component was derived
+// from the resources that were added to the parser.
+func (p *ComponentParser) Explain(name string) (Explanation, bool) {
+	e, ok := p.explanations[name]
+	if !ok {
+		return Explanation{}, false
+	}
+	result := *e
+	result.Fields = append([]FieldSource{
+		{Field: "apiVersion", Value: APIVersion, Source: "default"},
+		{Field: "kind", Value: KindComponent, Source: "default"},
+	}, e.Fields...)
+	return result, true
+}
+
+// explain records the sources of the fields parsed from obj for the
+// Component, and any values that were overridden.
+func (p *ComponentParser) explain(obj runtime.Object, labels, annotations map[string]string, c discoveryComponent) {
+	ref := referenceForObject(obj)
+	fields := fieldSources(&ref, labels, annotations, c)
+
+	if p.explanations == nil {
+		p.explanations = map[string]*Explanation{}
+	}
+	e, ok := p.explanations[c.name]
+	if !ok {
+		p.explanations[c.name] = &Explanation{Component: c.name, Objects: []ObjectReference{ref}, Fields: fields}
+		return
+	}
+
+	e.Objects = append(e.Objects, ref)
+	current := map[string]FieldSource{}
+	for _, f := range fields {
+		current[f.Field] = f
+	}
+	for _, prev := range e.Fields {
+		f, ok := current[prev.Field]
+		if !ok {
+			f = FieldSource{Field: prev.Field, Source: "not set", Object: &ref}
+		}
+		if f.Value != prev.Value {
+			e.Overrides = append(e.Overrides, Override{Field: prev.Field, Previous: prev, Current: f})
+		}
+	}
+	e.Fields = fields
+}
+
+func fieldSources(ref *ObjectReference, labels, annotations map[string]string, c discoveryComponent) []FieldSource {
+	fromLabel := func(field, key string) FieldSource {
+		return FieldSource{Field: field, Value: labels[key], Source: describeSource("label", key, labels), Object: ref}
+	}
+	fromAnnotation := func(field, key string) FieldSource {
+		return FieldSource{Field: field, Value: annotations[key], Source: describeSource("annotation", key, annotations), Object: ref}
+	}
+
+	fields := []FieldSource{
+		fromLabel("metadata.name", nameLabel),
+		fromAnnotation("metadata.description", DescriptionAnnotation),
+	}
+	tags := fromAnnotation("metadata.tags", tagsAnnotation)
+	tags.Value = strings.Join(c.tags, ",")
+	fields = append(fields, tags)
+
+	for _, k := range slices.Sorted(maps.Keys(c.annotations)) {
+		field := fmt.Sprintf("metadata.annotations[%s]", k)
+		// Keys in labels override keys in annotations.
+		if _, ok := labels[k]; ok {
+			fields = append(fields, fromLabel(field, k))
+			continue
+		}
+		fields = append(fields, fromAnnotation(field, k))
+	}
+
+	// Links that aren't in the form url,title,icon are dropped when parsing.
+	linkKeys := []string{}
+	for k, v := range annotations {
+		if strings.HasPrefix(k, urlAnnotationPrefix) && len(strings.SplitN(v, ",", 3)) == 3 {
+			linkKeys = append(linkKeys, k)
+		}
+	}
+	slices.SortFunc(linkKeys, func(a, b string) int {
+		return linkSequence(a) - linkSequence(b)
+	})
+	for i, link := range c.links {
+		fields = append(fields, FieldSource{
+			Field:  fmt.Sprintf("metadata.links[%d]", i),
+			Value:  strings.Join([]string{link.URL, link.Title, link.Icon}, ","),
+			Source: "annotation " + linkKeys[i],
+			Object: ref,
+		})
+	}
+
+	return append(fields,
+		fromLabel("spec.type", componentLabel),
+		fromAnnotation("spec.lifecycle", LifecycleAnnotation),
+		fromLabel("spec.owner", createdByLabel),
+		fromLabel("spec.system", partOfLabel),
+	)
+}
+
+// describeSource describes the label or annotation that a value came from,
+// noting when it's not set and the value is empty.
+func describeSource(kind, key string, values map[string]string) string {
+	if _, ok := values[key]; !ok {
+		return fmt.Sprintf("not set (%s %s)", kind, key)
+	}
+	return kind + " " + key
+}
+
+// linkSequence returns the sequence number of a link annotation, for
+// sorting in the same order as parseLinkAnnotations.
+func linkSequence(key string) int {
+	seq, _ := strconv.Atoi(strings.TrimPrefix(key, urlAnnotationPrefix))
+	return seq
+}
+
+// referenceForObject returns a reference to the object, typed objects from
+// lists don't always have their kind set, so this falls back to the name of
+// the type.
+func referenceForObject(obj runtime.Object) ObjectReference {
+	ref := ObjectReference{Kind: obj.GetObjectKind().GroupVersionKind().Kind}
+	if ref.Kind == "" {
+		t := reflect.TypeOf(obj)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		ref.Kind = t.Name()
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		ref.Namespace = accessor.GetNamespace()
+		ref.Name = accessor.GetName()
+	}
+	return ref
+}
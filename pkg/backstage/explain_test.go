@@ -0,0 +1,103 @@
+package backstage
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestExplain(t *testing.T) {
+	blue := test.NewDeployment("nginx-blue", "web",
+		test.WithLabels(map[string]string{
+			nameLabel:      "nginx",
+			createdByLabel: "team-a",
+			componentLabel: "web-server",
+		}),
+		test.WithAnnotations(map[string]string{
+			LifecycleAnnotation:           "production",
+			"backstage.io/techdocs-ref":   "dir:.",
+			"backstage.gitops.pro/link-1": "https://example.com/docs,Docs,docs",
+			"backstage.gitops.pro/link-0": "https://example.com/,Dashboard,dashboard",
+		}),
+	)
+	green := test.NewDeployment("nginx-green", "web",
+		test.WithLabels(map[string]string{
+			nameLabel:                    "nginx",
+			createdByLabel:               "team-b",
+			componentLabel:               "web-server",
+			"backstage.io/kubernetes-id": "nginx",
+		}),
+		test.WithAnnotations(map[string]string{
+			LifecycleAnnotation: "production",
+			tagsAnnotation:      "web, nginx",
+		}),
+	)
+	// Typed items in lists don't have their kind set.
+	green.Kind = ""
+
+	parser := NewComponentParser()
+	if err := parser.Add(&appsv1.DeploymentList{Items: []appsv1.Deployment{blue, green}}); err != nil {
+		t.Fatal(err)
+	}
+
+	blueRef := &ObjectReference{Kind: "Deployment", Namespace: "web", Name: "nginx-blue"}
+	greenRef := &ObjectReference{Kind: "Deployment", Namespace: "web", Name: "nginx-green"}
+	want := Explanation{
+		Component: "nginx",
+		Objects:   []ObjectReference{*blueRef, *greenRef},
+		Fields: []FieldSource{
+			{Field: "apiVersion", Value: APIVersion, Source: "default"},
+			{Field: "kind", Value: KindComponent, Source: "default"},
+			{Field: "metadata.name", Value: "nginx", Source: "label app.kubernetes.io/name", Object: greenRef},
+			{Field: "metadata.description", Source: "not set (annotation backstage.io/kubernetes-description)", Object: greenRef},
+			{Field: "metadata.tags", Value: "web,nginx", Source: "annotation backstage.io/kubernetes-tags", Object: greenRef},
+			{Field: "metadata.annotations[backstage.io/kubernetes-id]", Value: "nginx", Source: "label backstage.io/kubernetes-id", Object: greenRef},
+			{Field: "spec.type", Value: "web-server", Source: "label app.kubernetes.io/component", Object: greenRef},
+			{Field: "spec.lifecycle", Value: "production", Source: "annotation backstage.io/kubernetes-lifecycle", Object: greenRef},
+			{Field: "spec.owner", Value: "team-b", Source: "label app.kubernetes.io/created-by", Object: greenRef},
+			{Field: "spec.system", Source: "not set (label app.kubernetes.io/part-of)", Object: greenRef},
+		},
+		Overrides: []Override{
+			{
+				Field:    "metadata.tags",
+				Previous: FieldSource{Field: "metadata.tags", Source: "not set (annotation backstage.io/kubernetes-tags)", Object: blueRef},
+				Current:  FieldSource{Field: "metadata.tags", Value: "web,nginx", Source: "annotation backstage.io/kubernetes-tags", Object: greenRef},
+			},
+			{
+				Field:    "metadata.annotations[backstage.io/techdocs-ref]",
+				Previous: FieldSource{Field: "metadata.annotations[backstage.io/techdocs-ref]", Value: "dir:.", Source: "annotation backstage.io/techdocs-ref", Object: blueRef},
+				Current:  FieldSource{Field: "metadata.annotations[backstage.io/techdocs-ref]", Source: "not set", Object: greenRef},
+			},
+			{
+				Field:    "metadata.links[0]",
+				Previous: FieldSource{Field: "metadata.links[0]", Value: "https://example.com/,Dashboard,dashboard", Source: "annotation backstage.gitops.pro/link-0", Object: blueRef},
+				Current:  FieldSource{Field: "metadata.links[0]", Source: "not set", Object: greenRef},
+			},
+			{
+				Field:    "metadata.links[1]",
+				Previous: FieldSource{Field: "metadata.links[1]", Value: "https://example.com/docs,Docs,docs", Source: "annotation backstage.gitops.pro/link-1", Object: blueRef},
+				Current:  FieldSource{Field: "metadata.links[1]", Source: "not set", Object: greenRef},
+			},
+			{
+				Field:    "spec.owner",
+				Previous: FieldSource{Field: "spec.owner", Value: "team-a", Source: "label app.kubernetes.io/created-by", Object: blueRef},
+				Current:  FieldSource{Field: "spec.owner", Value: "team-b", Source: "label app.kubernetes.io/created-by", Object: greenRef},
+			},
+		},
+	}
+
+	got, ok := parser.Explain("nginx")
+	if !ok {
+		t.Fatal("no explanation for nginx")
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to explain:\n%s", diff)
+	}
+
+	if _, ok := parser.Explain("unknown"); ok {
+		t.Fatal("got an explanation for an unknown component")
+	}
+}
@@ -5,11 +5,19 @@ const (
 	// is part of an application.
 	AppLabel = "app.kubernetes.io/part-of"
 
+	// NameLabel is the Kubernetes recommended label for the name of an
+	// application, this is used as the name of the Component.
+	NameLabel = "app.kubernetes.io/name"
+
 	partOfLabel    = AppLabel
 	instanceLabel  = "app.kubernetes.io/instance"
-	nameLabel      = "app.kubernetes.io/name"
+	nameLabel      = NameLabel
 	componentLabel = "app.kubernetes.io/component"
 	createdByLabel = "app.kubernetes.io/created-by"
+
+	// ExportLabel can be used to opt resources in to being published in the
+	// catalog, when the server is configured to require it.
+	ExportLabel = "backstage.gitops.pro/export"
 )
 
 // Unofficial annotations.
@@ -24,6 +32,16 @@ const (
 	tagsAnnotation        = "backstage.io/kubernetes-tags"
 
 	urlAnnotationPrefix = "backstage.gitops.pro/link-"
+
+	// TombstoneAnnotation is added to Components whose resources have been
+	// removed from the cluster, but which are still being published during a
+	// grace period, the value is the time the resources were removed.
+	TombstoneAnnotation = "backstage.gitops.pro/tombstoned-at"
+
+	// ClusterAnnotation is added to Components discovered from named
+	// clusters, the value is a comma-separated list of the clusters that the
+	// Component was discovered in.
+	ClusterAnnotation = "backstage.io/kubernetes-cluster"
 )
 
 var parsedAnnotations = []string{
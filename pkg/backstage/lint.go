@@ -0,0 +1,266 @@
+package backstage
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Severity is the severity of a Problem.
+type Severity string
+
+const (
+	// SeverityError is used for problems that prevent a Component from being
+	// published or accepted by Backstage.
+	SeverityError Severity = "error"
+	// SeverityWarning is used for problems that may cause a Component to be
+	// published incorrectly.
+	SeverityWarning Severity = "warning"
+)
+
+// LintRule describes a check made by Lint.
+type LintRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// Rules checked by Lint.
+var (
+	RuleMissingName = LintRule{"missing-name", SeverityWarning,
+		"Resources without the " + nameLabel + " label are not published as Components."}
+	RuleInvalidName = LintRule{"invalid-name", SeverityError,
+		"Component names must be valid Backstage entity names."}
+	RuleMissingOwner = LintRule{"missing-owner", SeverityError,
+		"Backstage requires an owner, from the " + createdByLabel + " label."}
+	RuleMissingType = LintRule{"missing-type", SeverityError,
+		"Backstage requires a type, from the " + componentLabel + " label."}
+	RuleMissingLifecycle = LintRule{"missing-lifecycle", SeverityError,
+		"Backstage requires a lifecycle, from the " + LifecycleAnnotation + " annotation."}
+	RuleInvalidLinkKey = LintRule{"invalid-link-key", SeverityError,
+		"Link annotations must be numbered e.g. " + urlAnnotationPrefix + "0, other keys fail parsing of the catalog."}
+	RuleInvalidLink = LintRule{"invalid-link", SeverityWarning,
+		"Link annotations must be in the form url,title,icon, other links are dropped."}
+	RuleInvalidTag = LintRule{"invalid-tag", SeverityError,
+		"Tags must be valid Backstage tags."}
+	RuleConflictingValues = LintRule{"conflicting-values", SeverityWarning,
+		"Resources for the same Component should agree on its fields, the last value parsed is used."}
+	RuleUnknownAnnotation = LintRule{"unknown-annotation", SeverityWarning,
+		"Annotations with the backstage.io/ or backstage.gitops.pro/ prefixes should be known to Backstage or this tool."}
+)
+
+// LintRules are all the rules that are checked by Lint.
+var LintRules = []LintRule{
+	RuleMissingName,
+	RuleInvalidName,
+	RuleMissingOwner,
+	RuleMissingType,
+	RuleMissingLifecycle,
+	RuleInvalidLinkKey,
+	RuleInvalidLink,
+	RuleInvalidTag,
+	RuleConflictingValues,
+	RuleUnknownAnnotation,
+}
+
+// ObjectReference identifies a resource that a Problem was found in.
+type ObjectReference struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (r ObjectReference) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// Problem is a problem found by Lint.
+type Problem struct {
+	Rule      string            `json:"rule"`
+	Severity  Severity          `json:"severity"`
+	Message   string            `json:"message"`
+	Component string            `json:"component,omitempty"`
+	Objects   []ObjectReference `json:"objects"`
+}
+
+var (
+	// These are the formats used by the Backstage catalog model.
+	entityNamePattern = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$`)
+	tagPattern        = regexp.MustCompile(`^[a-z0-9:+#]+(-[a-z0-9:+#]+)*$`)
+)
+
+const maxNameLength = 63
+
+// knownAnnotations are the annotations that are understood by Backstage or
+// this tool, in addition to those that are parsed.
+var knownAnnotations = []string{
+	"backstage.io/kubernetes-id",
+	"backstage.io/kubernetes-namespace",
+	"backstage.io/kubernetes-label-selector",
+	"backstage.io/techdocs-ref",
+	"backstage.io/techdocs-entity",
+	"backstage.io/source-location",
+	"backstage.io/source-template",
+	"backstage.io/view-url",
+	"backstage.io/edit-url",
+	"backstage.io/orphan",
+	"backstage.io/managed-by-location",
+	"backstage.io/managed-by-origin-location",
+	"backstage.io/code-coverage",
+	"backstage.io/adr-location",
+	ExportLabel,
+	TombstoneAnnotation,
+}
+
+// Lint checks the labels and annotations on the objects for problems that
+// would prevent Components from being published correctly.
+//
+// Problems are returned in the order of the objects.
+func Lint(objs []unstructured.Unstructured) []Problem {
+	problems := []Problem{}
+	byComponent := map[string][]unstructured.Unstructured{}
+	for _, obj := range objs {
+		problems = append(problems, lintObject(obj)...)
+		if name := obj.GetLabels()[nameLabel]; name != "" {
+			byComponent[name] = append(byComponent[name], obj)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(byComponent)) {
+		problems = append(problems, lintConflicts(name, byComponent[name])...)
+	}
+
+	return problems
+}
+
+func lintObject(obj unstructured.Unstructured) []Problem {
+	labels := obj.GetLabels()
+	annotations := obj.GetAnnotations()
+	ref := referenceFor(obj)
+	component := labels[nameLabel]
+
+	problems := []Problem{}
+	report := func(rule LintRule, format string, args ...any) {
+		problems = append(problems, Problem{
+			Rule:      rule.ID,
+			Severity:  rule.Severity,
+			Message:   fmt.Sprintf(format, args...),
+			Component: component,
+			Objects:   []ObjectReference{ref},
+		})
+	}
+
+	if component == "" {
+		report(RuleMissingName, "no %s label, this is not published as a Component", nameLabel)
+		return problems
+	}
+	if len(component) > maxNameLength || !entityNamePattern.MatchString(component) {
+		report(RuleInvalidName, "%q is not a valid Backstage entity name", component)
+	}
+	if labels[createdByLabel] == "" {
+		report(RuleMissingOwner, "no %s label, Backstage requires an owner", createdByLabel)
+	}
+	if labels[componentLabel] == "" {
+		report(RuleMissingType, "no %s label, Backstage requires a type", componentLabel)
+	}
+	if annotations[LifecycleAnnotation] == "" {
+		report(RuleMissingLifecycle, "no %s annotation, Backstage requires a lifecycle", LifecycleAnnotation)
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(annotations)) {
+		if !strings.HasPrefix(k, urlAnnotationPrefix) {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(k, urlAnnotationPrefix)); err != nil {
+			report(RuleInvalidLinkKey, "link annotation %q is not numbered", k)
+			continue
+		}
+		if parts := strings.SplitN(annotations[k], ",", 3); len(parts) != 3 || strings.TrimSpace(parts[0]) == "" {
+			report(RuleInvalidLink, "link annotation %q is not in the form url,title,icon", k)
+		}
+	}
+
+	for _, v := range strings.Split(annotations[tagsAnnotation], ",") {
+		tag := strings.TrimSpace(v)
+		if tag != "" && (len(tag) > maxNameLength || !tagPattern.MatchString(tag)) {
+			report(RuleInvalidTag, "%q is not a valid Backstage tag", tag)
+		}
+	}
+
+	for _, k := range unknownAnnotations(labels, annotations) {
+		report(RuleUnknownAnnotation, "unknown annotation %q", k)
+	}
+
+	return problems
+}
+
+// lintConflicts reports fields that have different values in the objects
+// for a Component.
+func lintConflicts(component string, objs []unstructured.Unstructured) []Problem {
+	if len(objs) < 2 {
+		return nil
+	}
+	fields := []struct {
+		name  string
+		value func(labels, annotations map[string]string) string
+	}{
+		{"owner", func(l, _ map[string]string) string { return l[createdByLabel] }},
+		{"type", func(l, _ map[string]string) string { return l[componentLabel] }},
+		{"system", func(l, _ map[string]string) string { return l[partOfLabel] }},
+		{"lifecycle", func(_, a map[string]string) string { return a[LifecycleAnnotation] }},
+		{"description", func(_, a map[string]string) string { return a[DescriptionAnnotation] }},
+	}
+
+	refs := []ObjectReference{}
+	for _, obj := range objs {
+		refs = append(refs, referenceFor(obj))
+	}
+	problems := []Problem{}
+	for _, f := range fields {
+		values := []string{}
+		for _, obj := range objs {
+			if v := f.value(obj.GetLabels(), obj.GetAnnotations()); !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 1 {
+			problems = append(problems, Problem{
+				Rule:      RuleConflictingValues.ID,
+				Severity:  RuleConflictingValues.Severity,
+				Message:   fmt.Sprintf("conflicting values for %s: %q", f.name, values),
+				Component: component,
+				Objects:   refs,
+			})
+		}
+	}
+	return problems
+}
+
+func unknownAnnotations(labels, annotations map[string]string) []string {
+	unknown := []string{}
+	for _, src := range []map[string]string{labels, annotations} {
+		for _, k := range slices.Sorted(maps.Keys(src)) {
+			if !strings.HasPrefix(k, "backstage.io/") && !strings.HasPrefix(k, "backstage.gitops.pro/") {
+				continue
+			}
+			if strings.HasPrefix(k, urlAnnotationPrefix) || slices.Contains(parsedAnnotations, k) ||
+				slices.Contains(knownAnnotations, k) || slices.Contains(unknown, k) {
+				continue
+			}
+			unknown = append(unknown, k)
+		}
+	}
+	return unknown
+}
+
+func referenceFor(obj unstructured.Unstructured) ObjectReference {
+	return referenceForObject(&obj)
+}
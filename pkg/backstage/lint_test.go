@@ -0,0 +1,155 @@
+package backstage
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestLint(t *testing.T) {
+	validLabels := map[string]string{
+		nameLabel:      "mysql",
+		createdByLabel: "team-a",
+		componentLabel: "database",
+	}
+	validAnnotations := map[string]string{
+		LifecycleAnnotation: "production",
+	}
+	ref := ObjectReference{Kind: "Deployment", Namespace: "test-ns", Name: "test"}
+
+	lintTests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        []Problem
+	}{
+		{
+			name:        "valid",
+			labels:      validLabels,
+			annotations: validAnnotations,
+			want:        []Problem{},
+		},
+		{
+			name:        "missing name",
+			labels:      map[string]string{createdByLabel: "team-a"},
+			annotations: validAnnotations,
+			want: []Problem{
+				{Rule: "missing-name", Severity: SeverityWarning, Message: "no app.kubernetes.io/name label, this is not published as a Component", Objects: []ObjectReference{ref}},
+			},
+		},
+		{
+			name:   "missing required fields",
+			labels: map[string]string{nameLabel: "mysql"},
+			want: []Problem{
+				{Rule: "missing-owner", Severity: SeverityError, Message: "no app.kubernetes.io/created-by label, Backstage requires an owner", Component: "mysql", Objects: []ObjectReference{ref}},
+				{Rule: "missing-type", Severity: SeverityError, Message: "no app.kubernetes.io/component label, Backstage requires a type", Component: "mysql", Objects: []ObjectReference{ref}},
+				{Rule: "missing-lifecycle", Severity: SeverityError, Message: "no backstage.io/kubernetes-lifecycle annotation, Backstage requires a lifecycle", Component: "mysql", Objects: []ObjectReference{ref}},
+			},
+		},
+		{
+			name:        "invalid name",
+			labels:      merge(validLabels, map[string]string{nameLabel: "my_sql-"}),
+			annotations: validAnnotations,
+			want: []Problem{
+				{Rule: "invalid-name", Severity: SeverityError, Message: `"my_sql-" is not a valid Backstage entity name`, Component: "my_sql-", Objects: []ObjectReference{ref}},
+			},
+		},
+		{
+			name:   "invalid links",
+			labels: validLabels,
+			annotations: merge(validAnnotations, map[string]string{
+				"backstage.gitops.pro/link-0":     "https://example.com/,Example,web",
+				"backstage.gitops.pro/link-1":     "https://example.com/",
+				"backstage.gitops.pro/link-first": "https://example.com/,Example,web",
+			}),
+			want: []Problem{
+				{Rule: "invalid-link", Severity: SeverityWarning, Message: `link annotation "backstage.gitops.pro/link-1" is not in the form url,title,icon`, Component: "mysql", Objects: []ObjectReference{ref}},
+				{Rule: "invalid-link-key", Severity: SeverityError, Message: `link annotation "backstage.gitops.pro/link-first" is not numbered`, Component: "mysql", Objects: []ObjectReference{ref}},
+			},
+		},
+		{
+			name:        "invalid tags",
+			labels:      validLabels,
+			annotations: merge(validAnnotations, map[string]string{tagsAnnotation: "java, Data,data_store"}),
+			want: []Problem{
+				{Rule: "invalid-tag", Severity: SeverityError, Message: `"Data" is not a valid Backstage tag`, Component: "mysql", Objects: []ObjectReference{ref}},
+				{Rule: "invalid-tag", Severity: SeverityError, Message: `"data_store" is not a valid Backstage tag`, Component: "mysql", Objects: []ObjectReference{ref}},
+			},
+		},
+		{
+			name:   "unknown annotations",
+			labels: merge(validLabels, map[string]string{"backstage.io/kubernetes-id": "mysql", "backstage.io/unknown": "test"}),
+			annotations: merge(validAnnotations, map[string]string{
+				"backstage.io/techdocs-ref":      "dir:.",
+				"backstage.gitops.pro/lifecycle": "production",
+				"example.com/other":              "test",
+			}),
+			want: []Problem{
+				{Rule: "unknown-annotation", Severity: SeverityWarning, Message: `unknown annotation "backstage.io/unknown"`, Component: "mysql", Objects: []ObjectReference{ref}},
+				{Rule: "unknown-annotation", Severity: SeverityWarning, Message: `unknown annotation "backstage.gitops.pro/lifecycle"`, Component: "mysql", Objects: []ObjectReference{ref}},
+			},
+		},
+	}
+
+	for _, tt := range lintTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dep := test.NewDeployment("test", "test-ns", test.WithLabels(tt.labels), test.WithAnnotations(tt.annotations))
+			problems := Lint([]unstructured.Unstructured{toUnstructured(t, dep)})
+			if diff := cmp.Diff(tt.want, problems); diff != "" {
+				t.Fatalf("failed to lint:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLintConflicts(t *testing.T) {
+	annotations := map[string]string{LifecycleAnnotation: "production"}
+	first := test.NewDeployment("mysql-primary", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "mysql", createdByLabel: "team-a", componentLabel: "database"}),
+		test.WithAnnotations(annotations))
+	second := test.NewDeployment("mysql-replica", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "mysql", createdByLabel: "team-b", componentLabel: "database"}),
+		test.WithAnnotations(annotations))
+
+	problems := Lint([]unstructured.Unstructured{toUnstructured(t, first), toUnstructured(t, second)})
+
+	want := []Problem{
+		{
+			Rule:      "conflicting-values",
+			Severity:  SeverityWarning,
+			Message:   `conflicting values for owner: ["team-a" "team-b"]`,
+			Component: "mysql",
+			Objects: []ObjectReference{
+				{Kind: "Deployment", Namespace: "test-ns", Name: "mysql-primary"},
+				{Kind: "Deployment", Namespace: "test-ns", Name: "mysql-replica"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, problems); diff != "" {
+		t.Fatalf("failed to lint:\n%s", diff)
+	}
+}
+
+func toUnstructured(t *testing.T, dep appsv1.Deployment) unstructured.Unstructured {
+	t.Helper()
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&dep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unstructured.Unstructured{Object: obj}
+}
+
+func merge(ms ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, m := range ms {
+		for k, v := range m {
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -22,13 +22,13 @@ func NewLocation(name, description string, targets ...string) *Location {
 
 // Location is a representation of a Backstage Location.
 type Location struct {
-	APIVersion string            `yaml:"apiVersion"`
-	Kind       string            `yaml:"kind"`
-	Metadata   BackstageMetadata `yaml:"metadata"`
-	Spec       LocationSpec      `yaml:"spec,omitempty"`
+	APIVersion string            `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string            `json:"kind" yaml:"kind"`
+	Metadata   BackstageMetadata `json:"metadata" yaml:"metadata"`
+	Spec       LocationSpec      `json:"spec,omitempty" yaml:"spec,omitempty"`
 }
 
 // LocationSpec is the spec for Location resources.
 type LocationSpec struct {
-	Targets []string `yaml:"targets,omitempty"`
+	Targets []string `json:"targets,omitempty" yaml:"targets,omitempty"`
 }
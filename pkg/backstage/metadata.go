@@ -2,16 +2,16 @@ package backstage
 
 // BackstageMetadata is a struct that contains Backstage-specific metadata.
 type BackstageMetadata struct {
-	Name        string            `yaml:"name"`
-	Description string            `yaml:"description,omitempty"`
-	Annotations map[string]string `yaml:"annotations,omitempty"`
-	Tags        []string          `yaml:"tags,omitempty"`
-	Links       []Link            `yaml:"links,omitempty"`
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Tags        []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Links       []Link            `json:"links,omitempty" yaml:"links,omitempty"`
 }
 
 // Link is a link for users to access some facet of data for a component.
 type Link struct {
-	URL   string `yaml:"url"`
-	Title string `yaml:"title,omitempty"`
-	Icon  string `yaml:"icon,omitempty"`
+	URL   string `json:"url" yaml:"url"`
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	Icon  string `json:"icon,omitempty" yaml:"icon,omitempty"`
 }
@@ -0,0 +1,70 @@
+package backstage
+
+import (
+	"maps"
+	"slices"
+)
+
+// Overlay merges the fields of the overlay onto the Component, and returns
+// the merged Component, the Component and overlay are not modified.
+//
+// Fields are merged as follows:
+//   - scalar fields e.g. description and owner are overridden by values in
+//     the overlay that are not empty
+//   - tags are merged, with any tags in the overlay that are not already on
+//     the Component appended
+//   - links are merged, links in the overlay replace links with the same URL
+//     and other links are appended
+//   - annotations are the union of both, values in the overlay override the
+//     Component's values for the same key
+//
+// The name, apiVersion and kind of the Component are never changed.
+func Overlay(c, overlay Component) Component {
+	result := c
+	result.Metadata.Description = overlayValue(c.Metadata.Description, overlay.Metadata.Description)
+	result.Spec.Type = overlayValue(c.Spec.Type, overlay.Spec.Type)
+	result.Spec.Lifecycle = overlayValue(c.Spec.Lifecycle, overlay.Spec.Lifecycle)
+	result.Spec.Owner = overlayValue(c.Spec.Owner, overlay.Spec.Owner)
+	result.Spec.System = overlayValue(c.Spec.System, overlay.Spec.System)
+
+	if len(overlay.Metadata.Tags) > 0 {
+		tags := slices.Clone(c.Metadata.Tags)
+		for _, v := range overlay.Metadata.Tags {
+			if !slices.Contains(tags, v) {
+				tags = append(tags, v)
+			}
+		}
+		result.Metadata.Tags = tags
+	}
+
+	if len(overlay.Metadata.Links) > 0 {
+		links := slices.Clone(c.Metadata.Links)
+		for _, v := range overlay.Metadata.Links {
+			i := slices.IndexFunc(links, func(l Link) bool { return l.URL == v.URL })
+			if i == -1 {
+				links = append(links, v)
+				continue
+			}
+			links[i] = v
+		}
+		result.Metadata.Links = links
+	}
+
+	if len(overlay.Metadata.Annotations) > 0 {
+		annotations := maps.Clone(c.Metadata.Annotations)
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		maps.Copy(annotations, overlay.Metadata.Annotations)
+		result.Metadata.Annotations = annotations
+	}
+
+	return result
+}
+
+func overlayValue(current, overlay string) string {
+	if overlay != "" {
+		return overlay
+	}
+	return current
+}
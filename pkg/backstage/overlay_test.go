@@ -0,0 +1,111 @@
+package backstage
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOverlay(t *testing.T) {
+	discovered := func() Component {
+		return Component{
+			APIVersion: APIVersion,
+			Kind:       KindComponent,
+			Metadata: BackstageMetadata{
+				Name:        "nginx",
+				Description: "Web server",
+				Annotations: map[string]string{
+					"backstage.io/kubernetes-id": "nginx",
+					"backstage.io/techdocs-ref":  "dir:.",
+				},
+				Tags: []string{"web"},
+				Links: []Link{
+					{URL: "https://example.com/", Title: "Dashboard", Icon: "dashboard"},
+				},
+			},
+			Spec: ComponentSpec{
+				Type:      "web-server",
+				Lifecycle: "production",
+				Owner:     "team-a",
+			},
+		}
+	}
+
+	overlayTests := []struct {
+		name    string
+		overlay Component
+		want    func(c *Component)
+	}{
+		{
+			name:    "empty overlay",
+			overlay: Component{Metadata: BackstageMetadata{Name: "nginx"}},
+			want:    func(c *Component) {},
+		},
+		{
+			name: "scalars override",
+			overlay: Component{
+				Metadata: BackstageMetadata{Name: "other", Description: "A much longer description"},
+				Spec:     ComponentSpec{Owner: "group:team-b", System: "frontend"},
+			},
+			want: func(c *Component) {
+				c.Metadata.Description = "A much longer description"
+				c.Spec.Owner = "group:team-b"
+				c.Spec.System = "frontend"
+			},
+		},
+		{
+			name: "lists merge",
+			overlay: Component{
+				Metadata: BackstageMetadata{
+					Tags: []string{"nginx", "web"},
+					Links: []Link{
+						{URL: "https://example.com/runbook", Title: "Runbook"},
+						{URL: "https://example.com/", Title: "Grafana", Icon: "dashboard"},
+					},
+				},
+			},
+			want: func(c *Component) {
+				c.Metadata.Tags = []string{"web", "nginx"}
+				c.Metadata.Links = []Link{
+					{URL: "https://example.com/", Title: "Grafana", Icon: "dashboard"},
+					{URL: "https://example.com/runbook", Title: "Runbook"},
+				}
+			},
+		},
+		{
+			name: "annotations union",
+			overlay: Component{
+				Metadata: BackstageMetadata{
+					Annotations: map[string]string{
+						"backstage.io/techdocs-ref": "url:https://example.com/docs",
+						"github.com/project-slug":   "example/nginx",
+					},
+				},
+			},
+			want: func(c *Component) {
+				c.Metadata.Annotations = map[string]string{
+					"backstage.io/kubernetes-id": "nginx",
+					"backstage.io/techdocs-ref":  "url:https://example.com/docs",
+					"github.com/project-slug":    "example/nginx",
+				}
+			},
+		},
+	}
+
+	for _, tt := range overlayTests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := discovered()
+			tt.want(&want)
+			c := discovered()
+
+			got := Overlay(c, tt.overlay)
+
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("failed to overlay:\n%s", diff)
+			}
+			if diff := cmp.Diff(discovered(), c); diff != "" {
+				t.Fatalf("component was modified:\n%s", diff)
+			}
+		})
+	}
+}
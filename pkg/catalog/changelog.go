@@ -0,0 +1,333 @@
+package catalog
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+// ErrRevisionExpired is returned when the changes since a revision are no
+// longer available, clients should fetch a new snapshot.
+var ErrRevisionExpired = errors.New("revision is no longer available")
+
+// ChangeType is the type of change that happened to an entity.
+type ChangeType string
+
+const (
+	// ChangeAdded indicates that an entity was added to the catalog.
+	ChangeAdded ChangeType = "added"
+	// ChangeUpdated indicates that an existing entity was changed.
+	ChangeUpdated ChangeType = "updated"
+	// ChangeRemoved indicates that an entity was removed from the catalog.
+	ChangeRemoved ChangeType = "removed"
+)
+
+// Change is a single change to an entity in the catalog.
+type Change struct {
+	Revision uint64
+	Type     ChangeType
+	Name     string
+	// Entity is the state of the entity after the change, this is nil for
+	// removed entities.
+	Entity *backstage.Component
+}
+
+// Delta is the net set of changes to the catalog between two revisions.
+type Delta struct {
+	Added   []backstage.Component
+	Updated []backstage.Component
+	Removed []string
+}
+
+// ChangeLog records the changes to the entities in the catalog as it is
+// updated.
+//
+// A limited number of changes are kept, older changes are discarded.
+type ChangeLog struct {
+	mu        sync.RWMutex
+	entities  map[string]backstage.Component
+	changes   []Change
+	revision  uint64
+	compacted uint64
+	limit     int
+
+	gracePeriod        time.Duration
+	annotateTombstones bool
+	tombstones         map[string]time.Time
+	now                func() time.Time
+
+	subscribers map[chan struct{}]struct{}
+}
+
+// ChangeLogOption configures optional behaviour of the ChangeLog.
+type ChangeLogOption func(*ChangeLog)
+
+// WithGracePeriod keeps entities that disappear from the catalog for the
+// provided duration before they are removed, so that resources that are
+// briefly removed e.g. during a blue/green deployment don't cause the
+// entities to be removed and recreated.
+func WithGracePeriod(d time.Duration) ChangeLogOption {
+	return func(l *ChangeLog) {
+		l.gracePeriod = d
+	}
+}
+
+// WithTombstoneAnnotation marks entities that are being kept during the
+// grace period with the backstage.TombstoneAnnotation.
+func WithTombstoneAnnotation() ChangeLogOption {
+	return func(l *ChangeLog) {
+		l.annotateTombstones = true
+	}
+}
+
+// NewChangeLog creates and returns a new ChangeLog that keeps at most limit
+// changes, a negative limit is treated as zero.
+func NewChangeLog(limit int, opts ...ChangeLogOption) *ChangeLog {
+	l := &ChangeLog{
+		entities:    map[string]backstage.Component{},
+		limit:       max(limit, 0),
+		tombstones:  map[string]time.Time{},
+		now:         time.Now,
+		subscribers: map[chan struct{}]struct{}{},
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	return l
+}
+
+// Update replaces the entities in the catalog, recording the differences
+// between the current entities and the new entities.
+//
+// Entities that are missing from the components are kept until the grace
+// period has expired, if one is configured.
+//
+// The recorded changes are returned.
+func (l *ChangeLog) Update(components []backstage.Component) []Change {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	updated := map[string]backstage.Component{}
+	for _, v := range components {
+		updated[v.Metadata.Name] = v
+	}
+	next := maps.Clone(updated)
+
+	recorded := []Change{}
+	record := func(t ChangeType, name string, entity *backstage.Component) {
+		l.revision++
+		recorded = append(recorded, Change{Revision: l.revision, Type: t, Name: name, Entity: entity})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(updated)) {
+		entity := updated[name]
+		existing, ok := l.entities[name]
+		delete(l.tombstones, name)
+		switch {
+		case !ok:
+			record(ChangeAdded, name, &entity)
+		case !equality.Semantic.DeepEqual(existing, entity):
+			record(ChangeUpdated, name, &entity)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(l.entities)) {
+		if _, ok := updated[name]; ok {
+			continue
+		}
+		removedAt, tombstoned := l.tombstones[name]
+		switch {
+		case l.gracePeriod > 0 && !tombstoned:
+			l.tombstones[name] = now
+			entity := l.entities[name]
+			if l.annotateTombstones {
+				entity = withTombstone(entity, now)
+				record(ChangeUpdated, name, &entity)
+			}
+			next[name] = entity
+		case tombstoned && now.Sub(removedAt) < l.gracePeriod:
+			next[name] = l.entities[name]
+		default:
+			delete(l.tombstones, name)
+			record(ChangeRemoved, name, nil)
+		}
+	}
+
+	l.entities = next
+	l.changes = append(l.changes, recorded...)
+	if excess := len(l.changes) - l.limit; excess > 0 {
+		l.compacted = l.changes[excess-1].Revision
+		l.changes = append([]Change(nil), l.changes[excess:]...)
+	}
+
+	if len(recorded) > 0 {
+		for ch := range l.subscribers {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	return recorded
+}
+
+// Restore replaces the entities in the catalog and the revision, discarding
+// any recorded changes, e.g. when loading a snapshot at startup.
+//
+// Clients can continue to request the changes since the restored revision,
+// older revisions are expired.
+func (l *ChangeLog) Restore(components []backstage.Component, revision uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entities = map[string]backstage.Component{}
+	for _, v := range components {
+		l.entities[v.Metadata.Name] = v
+	}
+	l.changes = nil
+	l.tombstones = map[string]time.Time{}
+	l.revision = revision
+	l.compacted = revision
+}
+
+// NextExpiry returns the time that the next entity being kept during the
+// grace period is due to be removed, the entity is removed by the first
+// Update after this time.
+func (l *ChangeLog) NextExpiry() (time.Time, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var next time.Time
+	for _, removedAt := range l.tombstones {
+		if expiry := removedAt.Add(l.gracePeriod); next.IsZero() || expiry.Before(next) {
+			next = expiry
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Tombstones returns the entities that are being kept during the grace
+// period, sorted by name.
+func (l *ChangeLog) Tombstones() []backstage.Component {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := []backstage.Component{}
+	for _, name := range slices.Sorted(maps.Keys(l.tombstones)) {
+		result = append(result, l.entities[name])
+	}
+	return result
+}
+
+// Subscribe returns a channel that is signalled when changes are recorded,
+// and a function that must be called to stop the subscription.
+//
+// Notifications are coalesced, subscribers should use Changes to get the
+// changes since the last revision they saw.
+func (l *ChangeLog) Subscribe() (<-chan struct{}, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	l.subscribers[ch] = struct{}{}
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subscribers, ch)
+	}
+}
+
+// Snapshot returns the current entities in the catalog, sorted by name,
+// and the revision they were taken at.
+func (l *ChangeLog) Snapshot() ([]backstage.Component, uint64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := []backstage.Component{}
+	for _, name := range slices.Sorted(maps.Keys(l.entities)) {
+		result = append(result, l.entities[name])
+	}
+	return result, l.revision
+}
+
+// Changes returns the individual changes that were recorded after the
+// provided revision.
+//
+// If the revision is too old, or in the future, ErrRevisionExpired is
+// returned.
+func (l *ChangeLog) Changes(revision uint64) ([]Change, uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if revision < l.compacted || revision > l.revision {
+		return nil, l.revision, ErrRevisionExpired
+	}
+	result := []Change{}
+	for _, v := range l.changes {
+		if v.Revision > revision {
+			result = append(result, v)
+		}
+	}
+	return result, l.revision, nil
+}
+
+// Since returns the net changes to the catalog after the provided revision,
+// and the current revision.
+//
+// An entity that was added and then removed since the revision will not
+// appear at all.
+func (l *ChangeLog) Since(revision uint64) (Delta, uint64, error) {
+	changes, current, err := l.Changes(revision)
+	if err != nil {
+		return Delta{}, current, err
+	}
+
+	type netChange struct {
+		existedBefore bool
+		existsNow     bool
+		entity        *backstage.Component
+	}
+	net := map[string]*netChange{}
+	for _, v := range changes {
+		n, ok := net[v.Name]
+		if !ok {
+			n = &netChange{existedBefore: v.Type != ChangeAdded}
+			net[v.Name] = n
+		}
+		n.existsNow = v.Type != ChangeRemoved
+		n.entity = v.Entity
+	}
+
+	delta := Delta{Added: []backstage.Component{}, Updated: []backstage.Component{}, Removed: []string{}}
+	for _, name := range slices.Sorted(maps.Keys(net)) {
+		n := net[name]
+		switch {
+		case !n.existedBefore && n.existsNow:
+			delta.Added = append(delta.Added, *n.entity)
+		case n.existedBefore && n.existsNow:
+			delta.Updated = append(delta.Updated, *n.entity)
+		case n.existedBefore && !n.existsNow:
+			delta.Removed = append(delta.Removed, name)
+		}
+	}
+
+	return delta, current, nil
+}
+
+// withTombstone returns a copy of the entity annotated with the time that it
+// was removed.
+func withTombstone(entity backstage.Component, removedAt time.Time) backstage.Component {
+	entity.Metadata.Annotations = maps.Clone(entity.Metadata.Annotations)
+	if entity.Metadata.Annotations == nil {
+		entity.Metadata.Annotations = map[string]string{}
+	}
+	entity.Metadata.Annotations[backstage.TombstoneAnnotation] = removedAt.UTC().Format(time.RFC3339)
+	return entity
+}
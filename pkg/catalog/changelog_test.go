@@ -0,0 +1,195 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+func TestChangeLogUpdate(t *testing.T) {
+	cl := NewChangeLog(10)
+
+	changes := cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+	assertChanges(t, changes, []Change{
+		{Revision: 1, Type: ChangeAdded, Name: "mysql"},
+		{Revision: 2, Type: ChangeAdded, Name: "nginx"},
+	})
+
+	changes = cl.Update([]backstage.Component{newComponent("mysql", "team-b"), newComponent("nginx", "team-a")})
+	assertChanges(t, changes, []Change{
+		{Revision: 3, Type: ChangeUpdated, Name: "mysql"},
+	})
+
+	changes = cl.Update([]backstage.Component{newComponent("mysql", "team-b")})
+	assertChanges(t, changes, []Change{
+		{Revision: 4, Type: ChangeRemoved, Name: "nginx"},
+	})
+
+	entities, revision := cl.Snapshot()
+	if revision != 4 {
+		t.Errorf("got revision %v, want 4", revision)
+	}
+	if diff := cmp.Diff([]backstage.Component{newComponent("mysql", "team-b")}, entities); diff != "" {
+		t.Errorf("failed snapshot:\n%s", diff)
+	}
+}
+
+func TestChangeLogSince(t *testing.T) {
+	cl := NewChangeLog(10)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+	_, start := cl.Snapshot()
+
+	cl.Update([]backstage.Component{newComponent("mysql", "team-b"), newComponent("redis", "team-a")})
+	cl.Update([]backstage.Component{newComponent("mysql", "team-c"), newComponent("kafka", "team-a")})
+
+	delta, revision, err := cl.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != 8 {
+		t.Errorf("got revision %v, want 8", revision)
+	}
+	want := Delta{
+		Added:   []backstage.Component{newComponent("kafka", "team-a")},
+		Updated: []backstage.Component{newComponent("mysql", "team-c")},
+		Removed: []string{"nginx"},
+	}
+	if diff := cmp.Diff(want, delta); diff != "" {
+		t.Errorf("failed delta:\n%s", diff)
+	}
+
+	delta, _, err = cl.Since(revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Delta{Added: []backstage.Component{}, Updated: []backstage.Component{}, Removed: []string{}}, delta); diff != "" {
+		t.Errorf("failed empty delta:\n%s", diff)
+	}
+}
+
+func TestChangeLogSinceExpired(t *testing.T) {
+	cl := NewChangeLog(2)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+	cl.Update([]backstage.Component{newComponent("redis", "team-a")})
+
+	if _, _, err := cl.Since(0); !errors.Is(err, ErrRevisionExpired) {
+		t.Errorf("got error %v, want %v", err, ErrRevisionExpired)
+	}
+	if _, _, err := cl.Since(100); !errors.Is(err, ErrRevisionExpired) {
+		t.Errorf("got error %v, want %v", err, ErrRevisionExpired)
+	}
+	if _, _, err := cl.Since(3); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+}
+
+func TestChangeLogNegativeLimit(t *testing.T) {
+	cl := NewChangeLog(-1)
+
+	changes := cl.Update([]backstage.Component{newComponent("mysql", "team-a")})
+	assertChanges(t, changes, []Change{
+		{Revision: 1, Type: ChangeAdded, Name: "mysql"},
+	})
+
+	if _, _, err := cl.Since(0); !errors.Is(err, ErrRevisionExpired) {
+		t.Errorf("got error %v, want %v", err, ErrRevisionExpired)
+	}
+	if _, _, err := cl.Since(1); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+}
+
+func assertChanges(t *testing.T, got, want []Change) {
+	t.Helper()
+	if diff := cmp.Diff(want, got, cmp.Transformer("withoutEntity", func(c Change) Change {
+		c.Entity = nil
+		return c
+	})); diff != "" {
+		t.Fatalf("failed changes:\n%s", diff)
+	}
+}
+
+func newComponent(name, owner string) backstage.Component {
+	return backstage.Component{
+		APIVersion: backstage.APIVersion,
+		Kind:       backstage.KindComponent,
+		Metadata:   backstage.BackstageMetadata{Name: name},
+		Spec:       backstage.ComponentSpec{Owner: owner},
+	}
+}
+
+func TestChangeLogGracePeriod(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	cl := NewChangeLog(10, WithGracePeriod(time.Minute))
+	cl.now = func() time.Time { return now }
+
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+
+	now = now.Add(10 * time.Second)
+	if changes := cl.Update([]backstage.Component{newComponent("mysql", "team-a")}); len(changes) != 0 {
+		t.Fatalf("got %v changes, want none during the grace period", len(changes))
+	}
+	entities, _ := cl.Snapshot()
+	if diff := cmp.Diff([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")}, entities); diff != "" {
+		t.Errorf("failed snapshot:\n%s", diff)
+	}
+	if diff := cmp.Diff([]backstage.Component{newComponent("nginx", "team-a")}, cl.Tombstones()); diff != "" {
+		t.Errorf("failed tombstones:\n%s", diff)
+	}
+	expiry, ok := cl.NextExpiry()
+	if want := now.Add(time.Minute); !ok || !expiry.Equal(want) {
+		t.Errorf("got expiry %v, %v, want %v", expiry, ok, want)
+	}
+
+	// nginx comes back before the grace period expires.
+	now = now.Add(10 * time.Second)
+	if changes := cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")}); len(changes) != 0 {
+		t.Fatalf("got %v changes, want none when an entity returns", len(changes))
+	}
+	if _, ok := cl.NextExpiry(); ok {
+		t.Error("got an expiry, want none after the entity returned")
+	}
+
+	cl.Update([]backstage.Component{newComponent("nginx", "team-a")})
+	now = now.Add(59 * time.Second)
+	if changes := cl.Update([]backstage.Component{newComponent("nginx", "team-a")}); len(changes) != 0 {
+		t.Fatalf("got %v changes, want none during the grace period", len(changes))
+	}
+	now = now.Add(time.Second)
+	changes := cl.Update([]backstage.Component{newComponent("nginx", "team-a")})
+	assertChanges(t, changes, []Change{
+		{Revision: 3, Type: ChangeRemoved, Name: "mysql"},
+	})
+	if tombstones := cl.Tombstones(); len(tombstones) != 0 {
+		t.Errorf("got %v tombstones, want none", len(tombstones))
+	}
+}
+
+func TestChangeLogTombstoneAnnotation(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	cl := NewChangeLog(10, WithGracePeriod(time.Minute), WithTombstoneAnnotation())
+	cl.now = func() time.Time { return now }
+
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a")})
+	changes := cl.Update([]backstage.Component{})
+	assertChanges(t, changes, []Change{
+		{Revision: 2, Type: ChangeUpdated, Name: "mysql"},
+	})
+	want := newComponent("mysql", "team-a")
+	want.Metadata.Annotations = map[string]string{backstage.TombstoneAnnotation: "2024-03-01T12:00:00Z"}
+	if diff := cmp.Diff(&want, changes[0].Entity); diff != "" {
+		t.Errorf("failed to annotate tombstone:\n%s", diff)
+	}
+
+	changes = cl.Update([]backstage.Component{newComponent("mysql", "team-a")})
+	assertChanges(t, changes, []Change{
+		{Revision: 3, Type: ChangeUpdated, Name: "mysql"},
+	})
+	if diff := cmp.Diff(newComponent("mysql", "team-a"), *changes[0].Entity); diff != "" {
+		t.Errorf("failed to remove tombstone annotation:\n%s", diff)
+	}
+}
@@ -0,0 +1,174 @@
+package catalog
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+// ReasonClusterUnavailable indicates that resources couldn't be listed from
+// one of several clusters, Components from the other clusters are still
+// published.
+const ReasonClusterUnavailable = "ClusterUnavailable"
+
+// Cluster is a cluster that Components are discovered from.
+type Cluster struct {
+	// Name identifies the cluster in annotations, metrics and logs, this is
+	// empty when Components are only discovered from a single cluster.
+	Name   string
+	Client client.Reader
+}
+
+// DuplicatePolicy determines how Components with the same name that are
+// discovered in different clusters are published.
+type DuplicatePolicy string
+
+const (
+	// MergeDuplicates publishes a single Component, fields from clusters
+	// earlier in the list take precedence.
+	MergeDuplicates DuplicatePolicy = "merge"
+	// SeparateDuplicates publishes a Component for each cluster, with the
+	// name of the cluster appended to the name of the Component.
+	SeparateDuplicates DuplicatePolicy = "separate"
+)
+
+// DuplicatePolicies are the supported DuplicatePolicy values.
+var DuplicatePolicies = []string{string(MergeDuplicates), string(SeparateDuplicates)}
+
+// ClusterList is the result of listing resources in a cluster.
+type ClusterList struct {
+	Cluster string
+	List    *appsv1.DeploymentList
+	Err     error
+}
+
+// ListClusters lists the resources in each of the clusters concurrently
+// with ListNamespaces.
+//
+// The results are in the same order as the clusters, a failure listing one
+// cluster doesn't prevent the others from being listed.
+func ListClusters(ctx context.Context, clusters []Cluster, namespaces []string, opts ...client.ListOption) []ClusterList {
+	result := make([]ClusterList, len(clusters))
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list, err := ListNamespaces(ctx, c.Client, namespaces, opts...)
+			result[i] = ClusterList{Cluster: c.Name, List: list, Err: err}
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// ClusterComponents are the Components discovered in a cluster.
+type ClusterComponents struct {
+	Cluster    string
+	Components []backstage.Component
+}
+
+// Combine combines the Components discovered in each of the clusters into
+// a single catalog, sorted by name.
+//
+// Components from named clusters have the ClusterAnnotation added, and
+// Components with the same name in more than one cluster are merged or
+// separated according to the policy.
+//
+// When separating, a Component whose name is the same as the name given to a
+// separated Component is also separated, so that they are never merged.
+func Combine(policy DuplicatePolicy, discovered []ClusterComponents) []backstage.Component {
+	clusters := map[string][]string{}
+	for _, d := range discovered {
+		for _, c := range d.Components {
+			clusters[c.Metadata.Name] = append(clusters[c.Metadata.Name], d.Cluster)
+		}
+	}
+	separated := map[string]bool{}
+	if policy == SeparateDuplicates {
+		separated = separatedNames(clusters)
+	}
+
+	combined := map[string]backstage.Component{}
+	for _, d := range discovered {
+		for _, c := range d.Components {
+			if d.Cluster != "" {
+				c = withCluster(c, d.Cluster)
+				if separated[c.Metadata.Name] {
+					c.Metadata.Name = c.Metadata.Name + "-" + d.Cluster
+				}
+			}
+			if existing, ok := combined[c.Metadata.Name]; ok {
+				c = mergeClusters(existing, c)
+			}
+			combined[c.Metadata.Name] = c
+		}
+	}
+
+	result := make([]backstage.Component, 0, len(combined))
+	for _, k := range slices.Sorted(maps.Keys(combined)) {
+		result = append(result, combined[k])
+	}
+	return result
+}
+
+// separatedNames returns the names of the Components that are published
+// separately for each cluster they're discovered in.
+//
+// These are the names discovered in more than one cluster, and any names that
+// would clash with the name of a separated Component.
+func separatedNames(clusters map[string][]string) map[string]bool {
+	separated := map[string]bool{}
+	for name, in := range clusters {
+		if len(in) > 1 {
+			separated[name] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for name := range separated {
+			for _, cluster := range clusters[name] {
+				clash := name + "-" + cluster
+				if _, ok := clusters[clash]; ok && !separated[clash] {
+					separated[clash] = true
+					changed = true
+				}
+			}
+		}
+	}
+	return separated
+}
+
+// withCluster returns a copy of the Component annotated with the cluster it
+// was discovered in.
+func withCluster(c backstage.Component, cluster string) backstage.Component {
+	annotations := maps.Clone(c.Metadata.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[backstage.ClusterAnnotation] = cluster
+	c.Metadata.Annotations = annotations
+	return c
+}
+
+// mergeClusters merges a Component discovered in a later cluster into the
+// existing Component, the existing Component's fields take precedence.
+func mergeClusters(existing, c backstage.Component) backstage.Component {
+	merged := backstage.Overlay(c, existing)
+	clusters := existing.Metadata.Annotations[backstage.ClusterAnnotation]
+	if cluster := c.Metadata.Annotations[backstage.ClusterAnnotation]; cluster != "" &&
+		!slices.Contains(strings.Split(clusters, ","), cluster) {
+		clusters = clusters + "," + cluster
+	}
+	if clusters != "" {
+		merged.Metadata.Annotations[backstage.ClusterAnnotation] = clusters
+	}
+	return merged
+}
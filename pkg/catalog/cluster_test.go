@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestCombine(t *testing.T) {
+	inCluster := func(c backstage.Component, clusters string) backstage.Component {
+		c.Metadata.Annotations = map[string]string{backstage.ClusterAnnotation: clusters}
+		return c
+	}
+	withTags := func(c backstage.Component, tags ...string) backstage.Component {
+		c.Metadata.Tags = tags
+		return c
+	}
+	discovered := func() []ClusterComponents {
+		return []ClusterComponents{
+			{Cluster: "dev", Components: []backstage.Component{newComponent("mysql", "team-a"), withTags(newComponent("nginx", "team-b"), "dev")}},
+			{Cluster: "prod", Components: []backstage.Component{withTags(newComponent("nginx", "team-c"), "prod")}},
+		}
+	}
+
+	combineTests := []struct {
+		name       string
+		policy     DuplicatePolicy
+		discovered []ClusterComponents
+		want       []backstage.Component
+	}{
+		{
+			name:   "single unnamed cluster",
+			policy: MergeDuplicates,
+			discovered: []ClusterComponents{
+				{Components: []backstage.Component{newComponent("nginx", "team-b"), newComponent("mysql", "team-a")}},
+			},
+			want: []backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")},
+		},
+		{
+			name:       "merging duplicates",
+			policy:     MergeDuplicates,
+			discovered: discovered(),
+			want: []backstage.Component{
+				inCluster(newComponent("mysql", "team-a"), "dev"),
+				inCluster(withTags(newComponent("nginx", "team-b"), "prod", "dev"), "dev,prod"),
+			},
+		},
+		{
+			name:       "separating duplicates",
+			policy:     SeparateDuplicates,
+			discovered: discovered(),
+			want: []backstage.Component{
+				inCluster(newComponent("mysql", "team-a"), "dev"),
+				inCluster(withTags(newComponent("nginx-dev", "team-b"), "dev"), "dev"),
+				inCluster(withTags(newComponent("nginx-prod", "team-c"), "prod"), "prod"),
+			},
+		},
+		{
+			name:   "separating duplicates that clash with other components",
+			policy: SeparateDuplicates,
+			discovered: []ClusterComponents{
+				{Cluster: "dev", Components: []backstage.Component{newComponent("nginx", "team-b")}},
+				{Cluster: "prod", Components: []backstage.Component{newComponent("nginx", "team-c"), newComponent("nginx-dev", "team-d")}},
+			},
+			want: []backstage.Component{
+				inCluster(newComponent("nginx-dev", "team-b"), "dev"),
+				inCluster(newComponent("nginx-dev-prod", "team-d"), "prod"),
+				inCluster(newComponent("nginx-prod", "team-c"), "prod"),
+			},
+		},
+	}
+
+	for _, tt := range combineTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Combine(tt.policy, tt.discovered)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to combine components:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCombineDoesNotModifyComponents(t *testing.T) {
+	mysql := newComponent("mysql", "team-a")
+	mysql.Metadata.Annotations = map[string]string{backstage.LifecycleAnnotation: "production"}
+
+	Combine(MergeDuplicates, []ClusterComponents{
+		{Cluster: "dev", Components: []backstage.Component{mysql}},
+		{Cluster: "prod", Components: []backstage.Component{mysql}},
+	})
+
+	want := map[string]string{backstage.LifecycleAnnotation: "production"}
+	if diff := cmp.Diff(want, mysql.Metadata.Annotations); diff != "" {
+		t.Fatalf("component was modified:\n%s", diff)
+	}
+}
+
+func TestListClusters(t *testing.T) {
+	dep := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{backstage.NameLabel: "mysql"}),
+	)
+	failing := interceptor.NewClient(newFakeClient(t).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return errors.New("cluster is unavailable")
+		},
+	})
+
+	lists := ListClusters(context.TODO(), []Cluster{
+		{Name: "dev", Client: newFakeClient(t, &dep)},
+		{Name: "prod", Client: failing},
+	}, nil)
+
+	if len(lists) != 2 {
+		t.Fatalf("got %d lists, want 2", len(lists))
+	}
+	if lists[0].Cluster != "dev" || lists[0].Err != nil || len(lists[0].List.Items) != 1 {
+		t.Fatalf("failed to list dev cluster, got %#v", lists[0])
+	}
+	if lists[1].Cluster != "prod" || lists[1].Err == nil {
+		t.Fatalf("expected an error listing the prod cluster, got %#v", lists[1])
+	}
+}
@@ -0,0 +1,137 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+// Reasons for diagnostics recorded while building the catalog.
+const (
+	// ReasonListFailed indicates that resources couldn't be listed.
+	ReasonListFailed = "ListFailed"
+	// ReasonParseFailed indicates that resources couldn't be parsed.
+	ReasonParseFailed = "ParseFailed"
+)
+
+// deploymentKind is the kind of the resources that Components are
+// discovered from.
+var deploymentKind = appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind()
+
+// Discover lists the resources in the cluster and parses them into
+// Components.
+func Discover(ctx context.Context, c client.Reader, opts ...client.ListOption) ([]backstage.Component, error) {
+	list, err := List(ctx, c, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(list)
+}
+
+// List lists the resources that Components are discovered from.
+func List(ctx context.Context, c client.Reader, opts ...client.ListOption) (*appsv1.DeploymentList, error) {
+	var deploymentList appsv1.DeploymentList
+	if err := c.List(ctx, &deploymentList, opts...); err != nil {
+		return nil, fmt.Errorf("failed to load deployments: %w", err)
+	}
+
+	return &deploymentList, nil
+}
+
+// ListNamespaces lists the resources that Components are discovered from in
+// each of the namespaces, or in all namespaces if no namespaces are provided.
+//
+// This only needs permission to list the resources in the namespaces, rather
+// than across the cluster.
+func ListNamespaces(ctx context.Context, c client.Reader, namespaces []string, opts ...client.ListOption) (*appsv1.DeploymentList, error) {
+	result := &appsv1.DeploymentList{}
+	err := listInNamespaces(namespaces, opts, func(opts ...client.ListOption) error {
+		list, err := List(ctx, c, opts...)
+		if err != nil {
+			return err
+		}
+		result.Items = append(result.Items, list.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListObjects lists the resources that Components are discovered from in
+// the same way as ListNamespaces, returning them as unstructured objects so
+// that they can be handled in the same way as manifests.
+func ListObjects(ctx context.Context, c client.Reader, namespaces []string, opts ...client.ListOption) ([]unstructured.Unstructured, error) {
+	var objs []unstructured.Unstructured
+	err := listInNamespaces(namespaces, opts, func(opts ...client.ListOption) error {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("DeploymentList"))
+		if err := c.List(ctx, list, opts...); err != nil {
+			return fmt.Errorf("failed to load deployments: %w", err)
+		}
+		objs = append(objs, list.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// listInNamespaces calls list with the options limited to each of the
+// namespaces in turn, or once with the options if no namespaces are
+// provided.
+func listInNamespaces(namespaces []string, opts []client.ListOption, list func(...client.ListOption) error) error {
+	if len(namespaces) == 0 {
+		return list(opts...)
+	}
+	for _, ns := range namespaces {
+		// The options are cloned so that the namespace isn't appended to
+		// the caller's backing array.
+		if err := list(append(slices.Clone(opts), client.InNamespace(ns))...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseObjects parses the resources that Components are discovered from in
+// objs into Components, other objects are ignored.
+//
+// This allows Components to be generated from manifests, in the same way
+// that they are discovered from the cluster.
+func ParseObjects(objs []unstructured.Unstructured) ([]backstage.Component, error) {
+	list := &unstructured.UnstructuredList{}
+	for _, v := range objs {
+		if IsDiscovered(v) {
+			list.Items = append(list.Items, v)
+		}
+	}
+
+	return Parse(list)
+}
+
+// IsDiscovered returns true if Components are discovered from the kind of
+// the object.
+func IsDiscovered(obj unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == deploymentKind
+}
+
+// Parse parses the resources in a list into Components.
+func Parse(list runtime.Object) ([]backstage.Component, error) {
+	parser := backstage.NewComponentParser()
+	if err := parser.Add(list); err != nil {
+		return nil, fmt.Errorf("failed to parse deployments: %w", err)
+	}
+
+	return parser.Components(), nil
+}
@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestParseObjects(t *testing.T) {
+	objs := []unstructured.Unstructured{
+		newObject("apps/v1", "Deployment", map[string]any{
+			"app.kubernetes.io/name":       "mysql",
+			"app.kubernetes.io/created-by": "team-a",
+		}),
+		newObject("v1", "Service", map[string]any{
+			"app.kubernetes.io/name":       "nginx",
+			"app.kubernetes.io/created-by": "team-a",
+		}),
+		newObject("example.com/v1", "Deployment", map[string]any{
+			"app.kubernetes.io/name": "redis",
+		}),
+	}
+
+	components, err := ParseObjects(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []backstage.Component{
+		{
+			APIVersion: backstage.APIVersion,
+			Kind:       backstage.KindComponent,
+			Metadata: backstage.BackstageMetadata{
+				Name:        "mysql",
+				Tags:        []string{},
+				Annotations: map[string]string{},
+				Links:       []backstage.Link{},
+			},
+			Spec: backstage.ComponentSpec{Owner: "team-a"},
+		},
+	}
+	if diff := cmp.Diff(want, components); diff != "" {
+		t.Fatalf("failed to parse objects:\n%s", diff)
+	}
+}
+
+func newObject(apiVersion, kind string, labels map[string]any) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]any{
+			"name":   "test",
+			"labels": labels,
+		},
+	}}
+}
+
+func TestListNamespaces(t *testing.T) {
+	deps := []appsv1.Deployment{
+		test.NewDeployment("mysql", "team-a", test.WithLabels(map[string]string{"tier": "backend"})),
+		test.NewDeployment("nginx", "team-a", test.WithLabels(map[string]string{"tier": "frontend"})),
+		test.NewDeployment("redis", "team-b", test.WithLabels(map[string]string{"tier": "backend"})),
+		test.NewDeployment("kafka", "team-c", test.WithLabels(map[string]string{"tier": "backend"})),
+	}
+	fc := newFakeClient(t, &deps[0], &deps[1], &deps[2], &deps[3])
+
+	listTests := []struct {
+		name       string
+		namespaces []string
+		opts       []client.ListOption
+		want       []string
+	}{
+		{
+			name: "all namespaces",
+			want: []string{"team-a/mysql", "team-a/nginx", "team-b/redis", "team-c/kafka"},
+		},
+		{
+			name:       "some namespaces",
+			namespaces: []string{"team-b", "team-a"},
+			want:       []string{"team-b/redis", "team-a/mysql", "team-a/nginx"},
+		},
+		{
+			name:       "with selector",
+			namespaces: []string{"team-a", "team-b"},
+			opts:       []client.ListOption{client.MatchingLabels{"tier": "backend"}},
+			want:       []string{"team-a/mysql", "team-b/redis"},
+		},
+	}
+
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := ListNamespaces(context.TODO(), fc, tt.namespaces, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, v := range list.Items {
+				got = append(got, v.Namespace+"/"+v.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to list:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	deps := []appsv1.Deployment{
+		test.NewDeployment("mysql", "team-a", test.WithLabels(map[string]string{"tier": "backend"})),
+		test.NewDeployment("nginx", "team-a", test.WithLabels(map[string]string{"tier": "frontend"})),
+		test.NewDeployment("redis", "team-b", test.WithLabels(map[string]string{"tier": "backend"})),
+	}
+	fc := newFakeClient(t, &deps[0], &deps[1], &deps[2])
+
+	objs, err := ListObjects(context.TODO(), fc, []string{"team-a", "team-b"}, client.MatchingLabels{"tier": "backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, v := range objs {
+		got = append(got, v.GetKind()+"/"+v.GetNamespace()+"/"+v.GetName())
+	}
+	want := []string{"Deployment/team-a/mysql", "Deployment/team-b/redis"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to list:\n%s", diff)
+	}
+}
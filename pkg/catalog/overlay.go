@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+// Overlays are hand-written Components that are merged onto the discovered
+// Components with the same names, for fields that can't reasonably be
+// stored in annotations e.g. long descriptions.
+//
+// A nil Overlays doesn't change any Components.
+type Overlays struct {
+	mu       sync.RWMutex
+	overlays map[string]backstage.Component
+}
+
+// NewOverlays creates and returns Overlays for the Components.
+//
+// If there are multiple Components with the same name, the later ones are
+// merged onto the earlier ones.
+func NewOverlays(components []backstage.Component) *Overlays {
+	o := &Overlays{}
+	o.Replace(components)
+	return o
+}
+
+// Replace replaces all the overlays with the Components, this allows the
+// overlays to be reloaded while they're in use.
+func (o *Overlays) Replace(components []backstage.Component) {
+	overlays := map[string]backstage.Component{}
+	for _, v := range components {
+		if existing, ok := overlays[v.Metadata.Name]; ok {
+			v = backstage.Overlay(existing, v)
+		}
+		overlays[v.Metadata.Name] = v
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.overlays = overlays
+}
+
+// Len returns the number of Components that have overlays.
+func (o *Overlays) Len() int {
+	if o == nil {
+		return 0
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return len(o.overlays)
+}
+
+// Apply merges the overlays onto the Components with the same names.
+//
+// Overlays for Components that were not discovered are ignored.
+func (o *Overlays) Apply(components []backstage.Component) []backstage.Component {
+	if o == nil {
+		return components
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if len(o.overlays) == 0 {
+		return components
+	}
+	result := make([]backstage.Component, len(components))
+	for i, v := range components {
+		if overlay, ok := o.overlays[v.Metadata.Name]; ok {
+			v = backstage.Overlay(v, overlay)
+		}
+		result[i] = v
+	}
+	return result
+}
+
+// ReadOverlays reads the Components to overlay from the paths, which can be
+// files, or directories which are read recursively.
+func ReadOverlays(paths []string) ([]backstage.Component, error) {
+	result := []backstage.Component{}
+	for _, p := range paths {
+		files, err := manifests.Files(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, filename := range files {
+			components, err := readOverlayFile(filename)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, components...)
+		}
+	}
+	return result, nil
+}
+
+func readOverlayFile(filename string) ([]backstage.Component, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open overlay: %w", err)
+	}
+	defer f.Close()
+
+	components, err := backstage.DecodeComponents(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overlay %s: %w", filename, err)
+	}
+	return components, nil
+}
+
+// LoadOverlaysConfigMap loads the Components to overlay from the keys in a
+// ConfigMap, each key can contain multiple YAML documents.
+//
+// Keys are read in sorted order.
+func LoadOverlaysConfigMap(ctx context.Context, c client.Reader, key client.ObjectKey) ([]backstage.Component, error) {
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, key, &cm); err != nil {
+		return nil, fmt.Errorf("failed to load overlays from ConfigMap %s: %w", key, err)
+	}
+
+	result := []backstage.Component{}
+	for _, k := range slices.Sorted(maps.Keys(cm.Data)) {
+		components, err := backstage.DecodeComponents(strings.NewReader(cm.Data[k]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay %s in ConfigMap %s: %w", k, key, err)
+		}
+		result = append(result, components...)
+	}
+	return result, nil
+}
@@ -0,0 +1,167 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+const testOverlay = `apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: mysql
+  description: The user database
+  links:
+    - url: https://example.com/runbook
+      title: Runbook
+---
+apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: mysql
+  tags:
+    - database
+spec:
+  system: users
+`
+
+func TestOverlaysApply(t *testing.T) {
+	overlays := NewOverlays([]backstage.Component{
+		{Metadata: backstage.BackstageMetadata{Name: "mysql", Description: "The user database"}},
+		{Metadata: backstage.BackstageMetadata{Name: "mysql", Tags: []string{"database"}}},
+		{Metadata: backstage.BackstageMetadata{Name: "redis", Description: "Not discovered"}},
+	})
+	components := []backstage.Component{
+		newComponent("mysql", "team-a"),
+		newComponent("nginx", "team-b"),
+	}
+
+	got := overlays.Apply(components)
+
+	mysql := newComponent("mysql", "team-a")
+	mysql.Metadata.Description = "The user database"
+	mysql.Metadata.Tags = []string{"database"}
+	want := []backstage.Component{mysql, newComponent("nginx", "team-b")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to apply overlays:\n%s", diff)
+	}
+	if diff := cmp.Diff([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")}, components); diff != "" {
+		t.Fatalf("components were modified:\n%s", diff)
+	}
+}
+
+func TestOverlaysReplace(t *testing.T) {
+	overlays := NewOverlays([]backstage.Component{
+		{Metadata: backstage.BackstageMetadata{Name: "mysql", Description: "The user database"}},
+	})
+
+	overlays.Replace([]backstage.Component{
+		{Metadata: backstage.BackstageMetadata{Name: "nginx", Description: "The web server"}},
+	})
+
+	nginx := newComponent("nginx", "team-b")
+	nginx.Metadata.Description = "The web server"
+	want := []backstage.Component{newComponent("mysql", "team-a"), nginx}
+	got := overlays.Apply([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to replace overlays:\n%s", diff)
+	}
+}
+
+func TestOverlaysApplyWithNoOverlays(t *testing.T) {
+	components := []backstage.Component{newComponent("mysql", "team-a")}
+
+	var overlays *Overlays
+	if diff := cmp.Diff(components, overlays.Apply(components)); diff != "" {
+		t.Fatalf("failed to apply overlays:\n%s", diff)
+	}
+}
+
+func TestReadOverlays(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "mysql"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mysql", "catalog-info.yaml"), []byte(testOverlay), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlays, err := ReadOverlays([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(wantOverlays(), overlays); diff != "" {
+		t.Fatalf("failed to read overlays:\n%s", diff)
+	}
+}
+
+func TestReadOverlaysErrors(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "catalog-info.yaml")
+	if err := os.WriteFile(filename, []byte("kind: Component\nmetadata: [test]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadOverlays([]string{filename})
+	want := "failed to read overlay " + filename + ": failed to decode component: "
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestLoadOverlaysConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "overlays", Namespace: "backstage"},
+		Data: map[string]string{
+			"mysql.yaml": testOverlay,
+		},
+	}
+	fc := newFakeClient(t, cm)
+
+	overlays, err := LoadOverlaysConfigMap(context.TODO(), fc, client.ObjectKeyFromObject(cm))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(wantOverlays(), overlays); diff != "" {
+		t.Fatalf("failed to load overlays:\n%s", diff)
+	}
+
+	_, err = LoadOverlaysConfigMap(context.TODO(), fc, client.ObjectKey{Namespace: "backstage", Name: "unknown"})
+	if err == nil || err.Error() != `failed to load overlays from ConfigMap backstage/unknown: configmaps "unknown" not found` {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func wantOverlays() []backstage.Component {
+	return []backstage.Component{
+		{
+			APIVersion: backstage.APIVersion,
+			Kind:       backstage.KindComponent,
+			Metadata: backstage.BackstageMetadata{
+				Name:        "mysql",
+				Description: "The user database",
+				Links:       []backstage.Link{{URL: "https://example.com/runbook", Title: "Runbook"}},
+			},
+		},
+		{
+			APIVersion: backstage.APIVersion,
+			Kind:       backstage.KindComponent,
+			Metadata: backstage.BackstageMetadata{
+				Name: "mysql",
+				Tags: []string{"database"},
+			},
+			Spec: backstage.ComponentSpec{System: "users"},
+		},
+	}
+}
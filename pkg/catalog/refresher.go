@@ -0,0 +1,175 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/metrics"
+)
+
+// Refresher keeps a ChangeLog up to date with the resources in the cluster.
+//
+// Refreshes are triggered by changes to the watched resources, multiple
+// triggers while a refresh is in progress are coalesced into a single
+// refresh.
+type Refresher struct {
+	// Metrics records metrics for each refresh, this is optional.
+	Metrics *metrics.Metrics
+	// Overlays are merged onto the discovered components, this is optional.
+	Overlays *Overlays
+
+	logger      logr.Logger
+	clusters    []Cluster
+	duplicates  DuplicatePolicy
+	changes     *ChangeLog
+	listOptions []client.ListOption
+	trigger     chan struct{}
+	synced      atomic.Bool
+	// discovered is the last successfully discovered Components for each
+	// cluster, these are published while a cluster is unavailable.
+	discovered map[string][]backstage.Component
+}
+
+// NewRefresher creates and returns a new Refresher that discovers
+// components with the client and records them in the ChangeLog.
+//
+// The client would normally be backed by a cache.
+func NewRefresher(l logr.Logger, c client.Reader, changes *ChangeLog, opts ...client.ListOption) *Refresher {
+	return NewClusterRefresher(l, []Cluster{{Client: c}}, MergeDuplicates, changes, opts...)
+}
+
+// NewClusterRefresher creates and returns a new Refresher that discovers
+// components in each of the clusters, and combines them according to the
+// DuplicatePolicy.
+//
+// If a cluster can't be listed, the Components last discovered in that
+// cluster continue to be recorded.
+func NewClusterRefresher(l logr.Logger, clusters []Cluster, duplicates DuplicatePolicy, changes *ChangeLog, opts ...client.ListOption) *Refresher {
+	return &Refresher{
+		logger:      l,
+		clusters:    clusters,
+		duplicates:  duplicates,
+		changes:     changes,
+		listOptions: opts,
+		trigger:     make(chan struct{}, 1),
+		discovered:  map[string][]backstage.Component{},
+	}
+}
+
+// Trigger queues a refresh.
+func (r *Refresher) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// EventHandler returns an informer event handler that triggers a refresh
+// when resources are added, updated or deleted.
+func (r *Refresher) EventHandler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { r.Trigger() },
+		UpdateFunc: func(interface{}, interface{}) { r.Trigger() },
+		DeleteFunc: func(interface{}) { r.Trigger() },
+	}
+}
+
+// Refresh discovers the current components and records any changes.
+//
+// An error is only returned if none of the clusters could be refreshed,
+// this is not safe to call concurrently.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	discovered := []ClusterComponents{}
+	errs := []error{}
+	for _, v := range ListClusters(ctx, r.clusters, nil, r.listOptions...) {
+		components, err := r.parse(v)
+		if err != nil {
+			errs = append(errs, err)
+			if v.Cluster == "" {
+				continue
+			}
+			r.logger.Error(err, "failed to refresh cluster", "cluster", v.Cluster)
+			if last, ok := r.discovered[v.Cluster]; ok {
+				discovered = append(discovered, ClusterComponents{Cluster: v.Cluster, Components: last})
+			}
+			continue
+		}
+		r.discovered[v.Cluster] = components
+		discovered = append(discovered, ClusterComponents{Cluster: v.Cluster, Components: components})
+	}
+	if len(errs) == len(r.clusters) {
+		return errors.Join(errs...)
+	}
+
+	components := r.Overlays.Apply(Combine(r.duplicates, discovered))
+	r.Metrics.SetEntities(backstage.KindComponent, len(components))
+
+	if changes := r.changes.Update(components); len(changes) > 0 {
+		r.logger.Info("catalog updated", "changes", len(changes))
+	}
+	r.synced.Store(true)
+	return nil
+}
+
+// parse parses the Components from a cluster's list, recording metrics for
+// failures.
+func (r *Refresher) parse(l ClusterList) ([]backstage.Component, error) {
+	if l.Cluster != "" {
+		r.Metrics.SetClusterAvailable(l.Cluster, l.Err == nil)
+	}
+	if l.Err != nil {
+		r.Metrics.ListError("deployments")
+		if l.Cluster != "" {
+			r.Metrics.Diagnostic(ReasonClusterUnavailable)
+		} else {
+			r.Metrics.Diagnostic(ReasonListFailed)
+		}
+		return nil, l.Err
+	}
+
+	start := time.Now()
+	components, err := Parse(l.List)
+	r.Metrics.ObserveParse(time.Since(start))
+	if err != nil {
+		r.Metrics.Diagnostic(ReasonParseFailed)
+		return nil, err
+	}
+	return components, nil
+}
+
+// Synced returns true once the ChangeLog has been successfully refreshed.
+func (r *Refresher) Synced() bool {
+	return r.synced.Load()
+}
+
+// Start refreshes the ChangeLog, and then refreshes it again when triggered
+// until the context is cancelled.
+//
+// If entities are being kept during a grace period, a refresh is also
+// triggered when the grace period expires so that they are removed.
+func (r *Refresher) Start(ctx context.Context) error {
+	r.Trigger()
+	var expired <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.trigger:
+		case <-expired:
+		}
+		if err := r.Refresh(ctx); err != nil {
+			r.logger.Error(err, "failed to refresh catalog")
+		}
+		expired = nil
+		if next, ok := r.changes.NextExpiry(); ok {
+			expired = time.After(time.Until(next))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/zapr"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestRefresherRefresh(t *testing.T) {
+	dep := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{
+			"app.kubernetes.io/name":       "mysql",
+			"app.kubernetes.io/created-by": "team-a",
+		}),
+	)
+	fc := newFakeClient(t, &dep)
+	cl := NewChangeLog(10)
+	r := NewRefresher(zapr.NewLogger(zap.NewNop()), fc, cl)
+	if r.Synced() {
+		t.Fatal("refresher synced before refreshing")
+	}
+
+	if err := r.Refresh(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Synced() {
+		t.Fatal("refresher not synced after refreshing")
+	}
+
+	dep.Labels["app.kubernetes.io/created-by"] = "team-b"
+	if err := fc.Update(context.TODO(), &dep); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Refresh(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, _, err := cl.Changes(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChanges(t, changes, []Change{
+		{Revision: 1, Type: ChangeAdded, Name: "mysql"},
+		{Revision: 2, Type: ChangeUpdated, Name: "mysql"},
+	})
+	entities, _ := cl.Snapshot()
+	want := []string{"team-b"}
+	got := []string{}
+	for _, v := range entities {
+		got = append(got, v.Spec.Owner)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed to refresh:\n%s", diff)
+	}
+}
+
+func TestRefresherRefreshWithOverlays(t *testing.T) {
+	dep := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{
+			"app.kubernetes.io/name":       "mysql",
+			"app.kubernetes.io/created-by": "team-a",
+		}),
+	)
+	cl := NewChangeLog(10)
+	r := NewRefresher(zapr.NewLogger(zap.NewNop()), newFakeClient(t, &dep), cl)
+	r.Overlays = NewOverlays([]backstage.Component{
+		{Metadata: backstage.BackstageMetadata{Name: "mysql", Description: "The user database"}},
+	})
+
+	if err := r.Refresh(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	entities, _ := cl.Snapshot()
+	if len(entities) != 1 || entities[0].Metadata.Description != "The user database" {
+		t.Fatalf("overlay not applied, got %#v", entities)
+	}
+}
+
+func newFakeClient(t *testing.T, objs ...runtime.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(objs...).
+		Build()
+}
+
+func TestRefresherRefreshWithUnavailableCluster(t *testing.T) {
+	dev := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{backstage.NameLabel: "mysql"}),
+	)
+	prod := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{backstage.NameLabel: "nginx"}),
+	)
+	var failing atomic.Bool
+	prodClient := interceptor.NewClient(newFakeClient(t, &prod).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if failing.Load() {
+				return errors.New("cluster is unavailable")
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	devClient := newFakeClient(t, &dev)
+	cl := NewChangeLog(10)
+	r := NewClusterRefresher(zapr.NewLogger(zap.NewNop()), []Cluster{
+		{Name: "dev", Client: devClient},
+		{Name: "prod", Client: prodClient},
+	}, MergeDuplicates, cl)
+	if err := r.Refresh(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	failing.Store(true)
+	if err := devClient.Delete(context.TODO(), &dev); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Refresh(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	entities, _ := cl.Snapshot()
+	got := []string{}
+	for _, v := range entities {
+		got = append(got, v.Metadata.Name+"@"+v.Metadata.Annotations[backstage.ClusterAnnotation])
+	}
+	if diff := cmp.Diff([]string{"nginx@prod"}, got); diff != "" {
+		t.Fatalf("failed to refresh:\n%s", diff)
+	}
+}
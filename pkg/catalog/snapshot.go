@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+// snapshotVersion is the version of the snapshot file format.
+const snapshotVersion = 1
+
+// snapshotFile is the on-disk representation of a ChangeLog.
+type snapshotFile struct {
+	Version  int                   `json:"version"`
+	Revision uint64                `json:"revision"`
+	Entities []backstage.Component `json:"entities"`
+}
+
+// SaveSnapshot writes the current entities and revision of the ChangeLog to
+// the file at path.
+//
+// The file is replaced atomically, so a reader will never see a partially
+// written snapshot.
+func SaveSnapshot(path string, l *ChangeLog) error {
+	entities, revision := l.Snapshot()
+	b, err := json.Marshal(snapshotFile{Version: snapshotVersion, Revision: revision, Entities: entities})
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync snapshot file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot restores the ChangeLog from the snapshot in the file at path.
+//
+// If the file doesn't exist, the returned error wraps os.ErrNotExist.
+func LoadSnapshot(path string, l *ChangeLog) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	var s snapshotFile
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("failed to parse snapshot file %s: %w", path, err)
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d in %s", s.Version, path)
+	}
+	l.Restore(s.Entities, s.Revision)
+	return nil
+}
@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+func TestSaveAndLoadSnapshot(t *testing.T) {
+	mysql := newComponent("mysql", "team-a")
+	mysql.Metadata.Tags = []string{}
+	mysql.Metadata.Annotations = map[string]string{}
+	nginx := newComponent("nginx", "team-a")
+	nginx.Metadata.Links = []backstage.Link{{URL: "https://example.com/", Title: "Dashboard"}}
+
+	saved := NewChangeLog(10)
+	saved.Update([]backstage.Component{mysql, nginx})
+	saved.Update([]backstage.Component{mysql})
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := SaveSnapshot(path, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewChangeLog(10)
+	if err := LoadSnapshot(path, restored); err != nil {
+		t.Fatal(err)
+	}
+	entities, revision := restored.Snapshot()
+	if revision != 3 {
+		t.Errorf("got revision %v, want 3", revision)
+	}
+	if diff := cmp.Diff([]backstage.Component{newComponent("mysql", "team-a")}, entities); diff != "" {
+		t.Errorf("failed to restore entities:\n%s", diff)
+	}
+
+	// Updating with the same components records no changes, even though
+	// empty fields are not preserved in the snapshot.
+	if changes := restored.Update([]backstage.Component{mysql}); len(changes) != 0 {
+		t.Errorf("got %v changes, want none", len(changes))
+	}
+
+	restored.Update([]backstage.Component{mysql, nginx})
+	delta, revision, err := restored.Since(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision != 4 {
+		t.Errorf("got revision %v, want 4", revision)
+	}
+	want := Delta{Added: []backstage.Component{nginx}, Updated: []backstage.Component{}, Removed: []string{}}
+	if diff := cmp.Diff(want, delta); diff != "" {
+		t.Errorf("failed delta:\n%s", diff)
+	}
+	if _, _, err := restored.Since(2); !errors.Is(err, ErrRevisionExpired) {
+		t.Errorf("got error %v, want %v", err, ErrRevisionExpired)
+	}
+}
+
+func TestSaveSnapshotReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "catalog.json")
+	cl := NewChangeLog(10)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a")})
+	if err := SaveSnapshot(path, cl); err != nil {
+		t.Fatal(err)
+	}
+	cl.Update([]backstage.Component{newComponent("nginx", "team-a")})
+	if err := SaveSnapshot(path, cl); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %v files, want only the snapshot", len(files))
+	}
+	restored := NewChangeLog(10)
+	if err := LoadSnapshot(path, restored); err != nil {
+		t.Fatal(err)
+	}
+	if _, revision := restored.Snapshot(); revision != 3 {
+		t.Errorf("got revision %v, want 3", revision)
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := LoadSnapshot(filepath.Join(dir, "missing.json"), NewChangeLog(10)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got error %v, want %v", err, os.ErrNotExist)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"version":2,"revision":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadSnapshot(invalid, NewChangeLog(10)); err == nil {
+		t.Error("expected an error loading an unsupported version")
+	}
+}
@@ -0,0 +1,107 @@
+// Package config loads settings for commands from a config file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ignoredFlags are flags that can't be set in a config file.
+var ignoredFlags = []string{"config", "help"}
+
+// Read reads the config file and validates that the settings are known
+// flags, and that the values are valid for the types of the flags.
+//
+// The settings use the names of the flags e.g. "max-staleness: 10m", all the
+// problems that are found are reported in the returned error.
+func Read(filename string, flags *pflag.FlagSet) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+	}
+	if err := Validate(v, flags); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", filename, err)
+	}
+	return v, nil
+}
+
+// Validate checks that the settings in v are known flags, with values that
+// are valid for the types of the flags, and that numbers and durations are
+// not negative.
+func Validate(v *viper.Viper, flags *pflag.FlagSet) error {
+	errs := []error{}
+	keys := v.AllKeys()
+	slices.Sort(keys)
+	for _, key := range keys {
+		// Nested settings are flattened e.g. "listen.host", these are
+		// reported as invalid values if the parent is a known flag.
+		if parent, _, ok := strings.Cut(key, "."); ok && flags.Lookup(parent) != nil && !slices.Contains(ignoredFlags, parent) {
+			err := fmt.Errorf("invalid value for %q: expected a %s", parent, flags.Lookup(parent).Value.Type())
+			if !slices.ContainsFunc(errs, func(e error) bool { return e.Error() == err.Error() }) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		flag := flags.Lookup(key)
+		if flag == nil || slices.Contains(ignoredFlags, key) {
+			errs = append(errs, fmt.Errorf("unknown setting %q", key))
+			continue
+		}
+		if err := validateValue(flag.Value.Type(), v.Get(key)); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for %q: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckRanges checks that the values in v for the flags are valid for the
+// types of the flags, and that numbers and durations are not negative.
+//
+// Unlike Validate, this applies to the values from flags and environment
+// variables, as well as the config file.
+func CheckRanges(v *viper.Viper, flags *pflag.FlagSet) error {
+	errs := []error{}
+	flags.VisitAll(func(f *pflag.Flag) {
+		value := v.Get(f.Name)
+		if value == nil {
+			return
+		}
+		if err := validateValue(f.Value.Type(), value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --%s %v: %w", f.Name, value, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+var errNegative = errors.New("must not be negative")
+
+func validateValue(flagType string, value any) error {
+	var err error
+	switch flagType {
+	case "bool":
+		_, err = cast.ToBoolE(value)
+	case "int":
+		var n int
+		if n, err = cast.ToIntE(value); err == nil && n < 0 {
+			err = errNegative
+		}
+	case "duration":
+		var d time.Duration
+		if d, err = cast.ToDurationE(value); err == nil && d < 0 {
+			err = errNegative
+		}
+	case "stringSlice", "stringArray":
+		_, err = cast.ToStringSliceE(value)
+	default:
+		_, err = cast.ToStringE(value)
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestRead(t *testing.T) {
+	filename := writeConfig(t, `
+listen: :9080
+debug: true
+change-log-size: 500
+max-staleness: 10m
+overlay:
+  - ./overlays
+cluster:
+  - name=dev,context=dev-admin
+  - name=prod
+`)
+
+	v, err := Read(filename, testFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := v.GetString("listen"); got != ":9080" {
+		t.Errorf("got listen %q", got)
+	}
+	if got := v.GetDuration("max-staleness"); got != 10*time.Minute {
+		t.Errorf("got max-staleness %v", got)
+	}
+	if diff := cmp.Diff([]string{"./overlays"}, v.GetStringSlice("overlay")); diff != "" {
+		t.Errorf("got overlay:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"name=dev,context=dev-admin", "name=prod"}, v.GetStringSlice("cluster")); diff != "" {
+		t.Errorf("got cluster:\n%s", diff)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	readTests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "unknown settings",
+			config: `
+listen: :9080
+unknown: true
+tls:
+  cert-file: tls.crt
+config: other.yaml
+`,
+			want: []string{
+				`unknown setting "config"`,
+				`unknown setting "tls.cert-file"`,
+				`unknown setting "unknown"`,
+			},
+		},
+		{
+			name: "invalid values",
+			config: `
+debug: sometimes
+change-log-size: lots
+max-staleness: 5 minutes
+listen:
+  host: localhost
+  port: 8080
+`,
+			want: []string{
+				`invalid value for "change-log-size": unable to cast "lots"`,
+				`invalid value for "debug": strconv.ParseBool: parsing "sometimes": invalid syntax`,
+				`invalid value for "listen": expected a string`,
+				`invalid value for "max-staleness": time: unknown unit " minutes" in duration "5 minutes"`,
+			},
+		},
+		{
+			name: "negative values",
+			config: `
+change-log-size: -1
+max-staleness: -5m
+`,
+			want: []string{
+				`invalid value for "change-log-size": must not be negative`,
+				`invalid value for "max-staleness": must not be negative`,
+			},
+		},
+		{
+			name:   "invalid YAML",
+			config: "listen: [",
+			want:   []string{"failed to read config file"},
+		},
+	}
+
+	for _, tt := range readTests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeConfig(t, tt.config)
+
+			_, err := Read(filename, testFlags())
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadMissingFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "missing.yaml")
+
+	_, err := Read(filename, testFlags())
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to read config file "+filename) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestCheckRanges(t *testing.T) {
+	rangeTests := []struct {
+		name   string
+		values map[string]any
+		want   string
+	}{
+		{
+			name:   "valid values",
+			values: map[string]any{"change-log-size": 0, "max-staleness": "10m"},
+		},
+		{
+			name:   "negative int",
+			values: map[string]any{"change-log-size": -1},
+			want:   "invalid --change-log-size -1: must not be negative",
+		},
+		{
+			name:   "negative duration from the environment",
+			values: map[string]any{"max-staleness": "-1s"},
+			want:   "invalid --max-staleness -1s: must not be negative",
+		},
+		{
+			name:   "invalid type",
+			values: map[string]any{"change-log-size": "lots"},
+			want:   `invalid --change-log-size lots: unable to cast "lots"`,
+		},
+	}
+
+	for _, tt := range rangeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			for k, value := range tt.values {
+				v.Set(k, value)
+			}
+
+			err := CheckRanges(v, testFlags())
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("config", "", "")
+	flags.String("listen", "localhost:8080", "")
+	flags.Bool("debug", false, "")
+	flags.Int("change-log-size", 1000, "")
+	flags.Duration("max-staleness", 5*time.Minute, "")
+	flags.StringSlice("overlay", nil, "")
+	flags.StringArray("cluster", nil, "")
+	return flags
+}
+
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Watcher reloads the config file when it changes, or when it's triggered
+// e.g. when the process receives SIGHUP.
+type Watcher struct {
+	logger   logr.Logger
+	filename string
+	reload   func() error
+	trigger  chan struct{}
+	modTime  time.Time
+}
+
+// NewWatcher creates and returns a new Watcher that calls reload when the
+// file changes.
+func NewWatcher(l logr.Logger, filename string, reload func() error) (*Watcher, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+	}
+	return &Watcher{
+		logger:   l,
+		filename: filename,
+		reload:   reload,
+		trigger:  make(chan struct{}, 1),
+		modTime:  info.ModTime(),
+	}, nil
+}
+
+// Trigger queues a reload, even if the file hasn't changed.
+func (w *Watcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Start polls the file for changes at the interval, and reloads it when it
+// changes or a reload is triggered, until the context is cancelled.
+func (w *Watcher) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(w.filename)
+			if err != nil {
+				w.logger.Error(err, "failed to read config file", "path", w.filename)
+				continue
+			}
+			if info.ModTime().Equal(w.modTime) {
+				continue
+			}
+			w.modTime = info.ModTime()
+		case <-w.trigger:
+		}
+
+		if err := w.reload(); err != nil {
+			// Keep the previous configuration, the file may be part way
+			// through being updated.
+			w.logger.Error(err, "failed to reload config file", "path", w.filename)
+			continue
+		}
+		w.logger.Info("reloaded config file", "path", w.filename)
+	}
+}
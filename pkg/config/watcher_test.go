@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+)
+
+func TestWatcherReloadsChangedFile(t *testing.T) {
+	filename := writeConfig(t, "debug: false\n")
+	reloads := make(chan struct{}, 10)
+	w, err := NewWatcher(zapr.NewLogger(zap.NewNop()), filename, func() error {
+		reloads <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx, 10*time.Millisecond)
+
+	assertNoReload(t, reloads)
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filename, later, later); err != nil {
+		t.Fatal(err)
+	}
+	assertReload(t, reloads)
+	assertNoReload(t, reloads)
+}
+
+func TestWatcherTrigger(t *testing.T) {
+	filename := writeConfig(t, "debug: false\n")
+	reloads := make(chan struct{}, 10)
+	w, err := NewWatcher(zapr.NewLogger(zap.NewNop()), filename, func() error {
+		reloads <- struct{}{}
+		// Errors are logged, and don't stop the watcher.
+		return errors.New("invalid config")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx, time.Hour)
+
+	w.Trigger()
+	assertReload(t, reloads)
+	w.Trigger()
+	assertReload(t, reloads)
+}
+
+func TestNewWatcherWithMissingFile(t *testing.T) {
+	_, err := NewWatcher(zapr.NewLogger(zap.NewNop()), "testdata/missing.yaml", func() error { return nil })
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func assertReload(t *testing.T, reloads chan struct{}) {
+	t.Helper()
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("config was not reloaded")
+	}
+}
+
+func assertNoReload(t *testing.T, reloads chan struct{}) {
+	t.Helper()
+	select {
+	case <-reloads:
+		t.Fatal("config was reloaded")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
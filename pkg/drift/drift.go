@@ -0,0 +1,275 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+// Output formats for differences.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats are the supported output formats.
+var Formats = []string{FormatText, FormatJSON}
+
+// Type is the type of a Difference.
+type Type string
+
+const (
+	// Changed is used when the file and the generated Component have
+	// different values.
+	Changed Type = "changed"
+	// Missing is used when a value is generated, but isn't in the file.
+	Missing Type = "missing"
+	// Extra is used when a value is in the file, but isn't generated.
+	Extra Type = "extra"
+)
+
+// File is the Components that were read from a catalog file.
+type File struct {
+	Path       string
+	Components []backstage.Component
+	// Err is set when a file found in a directory couldn't be decoded, it
+	// has no Components.
+	Err error
+}
+
+// Difference is a difference between a Component in a file, and the
+// Component that is generated from resources.
+type Difference struct {
+	Component string `json:"component"`
+	// Field is empty when the whole Component is missing or extra.
+	Field     string `json:"field,omitempty"`
+	Type      Type   `json:"type"`
+	Existing  string `json:"existing,omitempty"`
+	Generated string `json:"generated,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+// Read reads the Components from the catalog files in the paths, which can
+// be files, or directories which are read recursively.
+//
+// Directories can contain other YAML and JSON files, so files in them that
+// can't be decoded are returned with the error rather than failing, only
+// files that are named in the paths must be decoded.
+func Read(paths []string) ([]File, error) {
+	result := []File{}
+	for _, p := range paths {
+		files, err := manifests.Files(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, filename := range files {
+			components, err := readFile(filename)
+			if err != nil {
+				// Files returns the path itself if it's not a directory.
+				if filename == p {
+					return nil, err
+				}
+				result = append(result, File{Path: filename, Err: err})
+				continue
+			}
+			result = append(result, File{Path: filename, Components: components})
+		}
+	}
+	return result, nil
+}
+
+func readFile(filename string) ([]backstage.Component, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	components, err := backstage.DecodeComponents(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return components, nil
+}
+
+// Compare compares the Components in the files with the generated
+// Components with the same names.
+//
+// Generated Components that aren't in any of the files are ignored, as a
+// repository usually only describes some of the Components.
+//
+// Annotations in the files are only compared if they could be generated,
+// i.e. they have the backstage.io/ prefix, so annotations for other plugins
+// are not reported.
+func Compare(files []File, generated []backstage.Component) []Difference {
+	byName := map[string]backstage.Component{}
+	for _, v := range generated {
+		byName[v.Metadata.Name] = v
+	}
+
+	result := []Difference{}
+	for _, f := range files {
+		for _, existing := range f.Components {
+			diffs := []Difference{{Type: Extra}}
+			if g, ok := byName[existing.Metadata.Name]; ok {
+				diffs = compareComponents(existing, g)
+			}
+			for _, d := range diffs {
+				d.Component = existing.Metadata.Name
+				d.Source = f.Path
+				result = append(result, d)
+			}
+		}
+	}
+	return result
+}
+
+func compareComponents(existing, generated backstage.Component) []Difference {
+	result := []Difference{}
+	add := func(d *Difference) {
+		if d != nil {
+			result = append(result, *d)
+		}
+	}
+
+	add(compareValue("metadata.description", existing.Metadata.Description, generated.Metadata.Description))
+	result = append(result, compareSets("metadata.tags", existing.Metadata.Tags, generated.Metadata.Tags)...)
+
+	keys := slices.Sorted(maps.Keys(generated.Metadata.Annotations))
+	for k := range existing.Metadata.Annotations {
+		if _, ok := generated.Metadata.Annotations[k]; !ok && strings.HasPrefix(k, "backstage.io/") {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		add(compareValue(fmt.Sprintf("metadata.annotations[%s]", k), existing.Metadata.Annotations[k], generated.Metadata.Annotations[k]))
+	}
+
+	result = append(result, compareLinks(existing.Metadata.Links, generated.Metadata.Links)...)
+
+	add(compareValue("spec.type", existing.Spec.Type, generated.Spec.Type))
+	add(compareValue("spec.lifecycle", existing.Spec.Lifecycle, generated.Spec.Lifecycle))
+	add(compareRef("spec.owner", existing.Spec.Owner, generated.Spec.Owner))
+	add(compareRef("spec.system", existing.Spec.System, generated.Spec.System))
+
+	return result
+}
+
+func compareValue(field, existing, generated string) *Difference {
+	switch {
+	case existing == generated:
+		return nil
+	case existing == "":
+		return &Difference{Field: field, Type: Missing, Generated: generated}
+	case generated == "":
+		return &Difference{Field: field, Type: Extra, Existing: existing}
+	}
+	return &Difference{Field: field, Type: Changed, Existing: existing, Generated: generated}
+}
+
+// compareRef compares entity references, which can be written in full in
+// files e.g. group:default/team-a, but are generated as just the name.
+func compareRef(field, existing, generated string) *Difference {
+	if refName(existing) == refName(generated) {
+		return nil
+	}
+	return compareValue(field, existing, generated)
+}
+
+func refName(ref string) string {
+	if _, name, ok := strings.Cut(ref, ":"); ok {
+		ref = name
+	}
+	return strings.TrimPrefix(ref, "default/")
+}
+
+func compareSets(field string, existing, generated []string) []Difference {
+	result := []Difference{}
+	for _, v := range generated {
+		if !slices.Contains(existing, v) {
+			result = append(result, Difference{Field: field, Type: Missing, Generated: v})
+		}
+	}
+	for _, v := range existing {
+		if !slices.Contains(generated, v) {
+			result = append(result, Difference{Field: field, Type: Extra, Existing: v})
+		}
+	}
+	return result
+}
+
+// compareLinks compares the links with the same URLs.
+func compareLinks(existing, generated []backstage.Link) []Difference {
+	const field = "metadata.links"
+	find := func(links []backstage.Link, url string) (backstage.Link, bool) {
+		i := slices.IndexFunc(links, func(l backstage.Link) bool { return l.URL == url })
+		if i == -1 {
+			return backstage.Link{}, false
+		}
+		return links[i], true
+	}
+
+	result := []Difference{}
+	for _, g := range generated {
+		e, ok := find(existing, g.URL)
+		switch {
+		case !ok:
+			result = append(result, Difference{Field: field, Type: Missing, Generated: linkString(g)})
+		case e != g:
+			result = append(result, Difference{Field: field, Type: Changed, Existing: linkString(e), Generated: linkString(g)})
+		}
+	}
+	for _, e := range existing {
+		if _, ok := find(generated, e.URL); !ok {
+			result = append(result, Difference{Field: field, Type: Extra, Existing: linkString(e)})
+		}
+	}
+	return result
+}
+
+// linkString formats links in the same way as the link annotations.
+func linkString(l backstage.Link) string {
+	return strings.Join([]string{l.URL, l.Title, l.Icon}, ",")
+}
+
+// Write writes the differences to w in the format.
+func Write(w io.Writer, format string, diffs []Difference) error {
+	switch format {
+	case FormatText:
+		return writeText(w, diffs)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diffs)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func writeText(w io.Writer, diffs []Difference) error {
+	for _, d := range diffs {
+		var line string
+		switch {
+		case d.Field == "":
+			line = "component is not generated from any resources"
+		case d.Type == Missing:
+			line = fmt.Sprintf("%s: %q", d.Field, d.Generated)
+		case d.Type == Extra:
+			line = fmt.Sprintf("%s: %q", d.Field, d.Existing)
+		default:
+			line = fmt.Sprintf("%s: %q in the file, %q generated", d.Field, d.Existing, d.Generated)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s %s: %s\n", d.Source, d.Type, d.Component, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d differences\n", len(diffs))
+	return err
+}
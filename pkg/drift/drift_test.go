@@ -0,0 +1,201 @@
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+const catalogInfo = `apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: nginx
+  description: Web server
+  annotations:
+    backstage.io/techdocs-ref: dir:.
+    github.com/project-slug: example/nginx
+  tags:
+    - web
+    - legacy
+  links:
+    - url: https://example.com/
+      title: Dashboard
+      icon: dashboard
+    - url: https://example.com/old
+      title: Old
+spec:
+  type: web-server
+  lifecycle: production
+  owner: group:default/team-a
+---
+apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+  name: mysql
+spec:
+  type: database
+  lifecycle: production
+  owner: team-a
+`
+
+func TestCompare(t *testing.T) {
+	files, source := readCatalog(t)
+	generated := []backstage.Component{
+		{
+			APIVersion: backstage.APIVersion,
+			Kind:       backstage.KindComponent,
+			Metadata: backstage.BackstageMetadata{
+				Name:        "nginx",
+				Description: "Web server",
+				Annotations: map[string]string{"backstage.io/kubernetes-id": "nginx"},
+				Tags:        []string{"web", "nginx"},
+				Links: []backstage.Link{
+					{URL: "https://example.com/", Title: "Dashboard", Icon: "web"},
+					{URL: "https://example.com/docs", Title: "Docs", Icon: "docs"},
+				},
+			},
+			Spec: backstage.ComponentSpec{
+				Type:      "web-server",
+				Lifecycle: "production",
+				Owner:     "team-b",
+				System:    "frontend",
+			},
+		},
+		{
+			APIVersion: backstage.APIVersion,
+			Kind:       backstage.KindComponent,
+			Metadata:   backstage.BackstageMetadata{Name: "redis"},
+		},
+	}
+
+	want := []Difference{
+		{Component: "nginx", Field: "metadata.tags", Type: Missing, Generated: "nginx", Source: source},
+		{Component: "nginx", Field: "metadata.tags", Type: Extra, Existing: "legacy", Source: source},
+		{Component: "nginx", Field: "metadata.annotations[backstage.io/kubernetes-id]", Type: Missing, Generated: "nginx", Source: source},
+		{Component: "nginx", Field: "metadata.annotations[backstage.io/techdocs-ref]", Type: Extra, Existing: "dir:.", Source: source},
+		{Component: "nginx", Field: "metadata.links", Type: Changed, Existing: "https://example.com/,Dashboard,dashboard", Generated: "https://example.com/,Dashboard,web", Source: source},
+		{Component: "nginx", Field: "metadata.links", Type: Missing, Generated: "https://example.com/docs,Docs,docs", Source: source},
+		{Component: "nginx", Field: "metadata.links", Type: Extra, Existing: "https://example.com/old,Old,", Source: source},
+		{Component: "nginx", Field: "spec.owner", Type: Changed, Existing: "group:default/team-a", Generated: "team-b", Source: source},
+		{Component: "nginx", Field: "spec.system", Type: Missing, Generated: "frontend", Source: source},
+		{Component: "mysql", Type: Extra, Source: source},
+	}
+	if diff := cmp.Diff(want, Compare(files, generated)); diff != "" {
+		t.Fatalf("failed to compare:\n%s", diff)
+	}
+}
+
+func TestCompareMatchingComponents(t *testing.T) {
+	files, _ := readCatalog(t)
+	generated := []backstage.Component{}
+	for _, v := range files[0].Components {
+		// Owners can be entity references in files.
+		v.Spec.Owner = "team-a"
+		generated = append(generated, v)
+	}
+
+	if diff := cmp.Diff([]Difference{}, Compare(files, generated)); diff != "" {
+		t.Fatalf("failed to compare:\n%s", diff)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	diffs := []Difference{
+		{Component: "nginx", Field: "metadata.tags", Type: Missing, Generated: "nginx", Source: "catalog-info.yaml"},
+		{Component: "nginx", Field: "metadata.tags", Type: Extra, Existing: "legacy", Source: "catalog-info.yaml"},
+		{Component: "nginx", Field: "spec.owner", Type: Changed, Existing: "team-a", Generated: "team-b", Source: "catalog-info.yaml"},
+		{Component: "mysql", Type: Extra, Source: "catalog-info.yaml"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatText, diffs); err != nil {
+		t.Fatal(err)
+	}
+	want := `catalog-info.yaml: missing nginx: metadata.tags: "nginx"
+catalog-info.yaml: extra nginx: metadata.tags: "legacy"
+catalog-info.yaml: changed nginx: spec.owner: "team-a" in the file, "team-b" generated
+catalog-info.yaml: extra mysql: component is not generated from any resources
+4 differences
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("failed to write text:\n%s", diff)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatJSON, diffs); err != nil {
+		t.Fatal(err)
+	}
+	var got []Difference
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(diffs, got); diff != "" {
+		t.Fatalf("failed to write JSON:\n%s", diff)
+	}
+
+	if err := Write(&buf, "xml", diffs); err == nil || err.Error() != `unknown format "xml"` {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+// readCatalog writes the catalog to a directory, and reads it back,
+// returning the files and the path of the catalog file.
+func TestReadSkipsUndecodableFilesInDirectories(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "catalog-info.yaml")
+	writeFile(t, filename, catalogInfo)
+	invalid := filepath.Join(dir, "values.yaml")
+	writeFile(t, invalid, "image: [\n")
+
+	files, err := Read([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	if files[0].Path != filename || len(files[0].Components) != 2 || files[0].Err != nil {
+		t.Errorf("failed to read %s, got %#v", filename, files[0])
+	}
+	if files[1].Path != invalid || files[1].Err == nil {
+		t.Errorf("expected an error reading %s, got %#v", invalid, files[1])
+	}
+}
+
+func TestReadUndecodableFile(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "catalog-info.yaml")
+	writeFile(t, invalid, "image: [\n")
+
+	_, err := Read([]string{invalid})
+
+	if err == nil {
+		t.Fatal("expected an error reading an undecodable file")
+	}
+}
+
+func readCatalog(t *testing.T) ([]File, string) {
+	t.Helper()
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "catalog-info.yaml")
+	writeFile(t, filename, catalogInfo)
+
+	files, err := Read([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files, filename
+}
+
+func writeFile(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,167 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+const (
+	// CatalogInfoFile is the name of the root Location file.
+	CatalogInfoFile = "catalog-info.yaml"
+
+	componentDir  = "component"
+	componentFile = "info.yaml"
+)
+
+// ComponentPath returns the path of the file for a component, relative to
+// the root Location, this matches the targets served by the HTTP API.
+func ComponentPath(name string) string {
+	return path.Join(componentDir, name, componentFile)
+}
+
+// Files returns the contents of a root Location file that targets a file
+// for each of the components, and the files for the components, keyed by
+// their path relative to the root.
+func Files(name, description string, components []backstage.Component) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	targets := []string{}
+	for _, v := range components {
+		b, err := marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		p := ComponentPath(v.Metadata.Name)
+		files[p] = b
+		targets = append(targets, "./"+p)
+	}
+
+	b, err := marshal(backstage.NewLocation(name, description, targets...))
+	if err != nil {
+		return nil, err
+	}
+	files[CatalogInfoFile] = b
+
+	return files, nil
+}
+
+// MultiDocument returns a single YAML file with a document for each of the
+// components.
+func MultiDocument(components []backstage.Component) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	for _, v := range components {
+		if err := enc.Encode(v); err != nil {
+			return nil, fmt.Errorf("failed to marshal component %s: %w", v.Metadata.Name, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal components: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteDir writes the files to the directory, creating it if necessary.
+//
+// Files that already have the same content are not rewritten, and component
+// files that are no longer in the catalog are removed.
+//
+// The paths of the files that were written or removed are returned, sorted.
+func WriteDir(dir string, files map[string][]byte) ([]string, error) {
+	changed := []string{}
+	for _, p := range slices.Sorted(maps.Keys(files)) {
+		written, err := WriteFile(filepath.Join(dir, filepath.FromSlash(p)), files[p])
+		if err != nil {
+			return nil, err
+		}
+		if written {
+			changed = append(changed, p)
+		}
+	}
+
+	stale, err := staleComponentFiles(dir, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range stale {
+		full := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.Remove(full); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", full, err)
+		}
+		// The directory is only removed if it's empty.
+		_ = os.Remove(filepath.Dir(full))
+		changed = append(changed, p)
+	}
+	slices.Sort(changed)
+
+	return changed, nil
+}
+
+// WriteFile writes the data to the file if the content has changed,
+// returning true if the file was written.
+func WriteFile(filename string, data []byte) (bool, error) {
+	existing, err := os.ReadFile(filename)
+	if err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return false, fmt.Errorf("failed to create directory for %s: %w", filename, err)
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return true, nil
+}
+
+// staleComponentFiles returns the component files in the directory that
+// are not in files.
+func staleComponentFiles(dir string, files map[string][]byte) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, componentDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read component directory: %w", err)
+	}
+
+	stale := []string{}
+	for _, v := range entries {
+		if !v.IsDir() {
+			continue
+		}
+		p := ComponentPath(v.Name())
+		if _, ok := files[p]; ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p))); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to check %s: %w", p, err)
+		}
+		stale = append(stale, p)
+	}
+	return stale, nil
+}
+
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := yaml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to marshal entity: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+func TestFiles(t *testing.T) {
+	files, err := Files("my-cluster", "Components in my-cluster",
+		[]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"catalog-info.yaml": `apiVersion: backstage.io/v1alpha1
+kind: Location
+metadata:
+    name: my-cluster
+    description: Components in my-cluster
+spec:
+    targets:
+        - ./component/mysql/info.yaml
+        - ./component/nginx/info.yaml
+`,
+		"component/mysql/info.yaml": `apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+    name: mysql
+spec:
+    type: ""
+    lifecycle: ""
+    owner: team-a
+    system: ""
+`,
+		"component/nginx/info.yaml": `apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+    name: nginx
+spec:
+    type: ""
+    lifecycle: ""
+    owner: team-b
+    system: ""
+`,
+	}
+	if diff := cmp.Diff(want, stringValues(files)); diff != "" {
+		t.Fatalf("failed to generate files:\n%s", diff)
+	}
+}
+
+func TestMultiDocument(t *testing.T) {
+	b, err := MultiDocument([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+    name: mysql
+spec:
+    type: ""
+    lifecycle: ""
+    owner: team-a
+    system: ""
+---
+apiVersion: backstage.io/v1alpha1
+kind: Component
+metadata:
+    name: nginx
+spec:
+    type: ""
+    lifecycle: ""
+    owner: team-b
+    system: ""
+`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Fatalf("failed to generate document:\n%s", diff)
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	files, err := Files("my-cluster", "", []backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := WriteDir(dir, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"catalog-info.yaml", "component/mysql/info.yaml", "component/nginx/info.yaml"}
+	if diff := cmp.Diff(want, changed); diff != "" {
+		t.Fatalf("failed to write files:\n%s", diff)
+	}
+
+	changed, err = WriteDir(dir, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{}, changed); diff != "" {
+		t.Fatalf("rewrote unchanged files:\n%s", diff)
+	}
+
+	files, err = Files("my-cluster", "", []backstage.Component{newComponent("mysql", "team-c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err = WriteDir(dir, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"catalog-info.yaml", "component/mysql/info.yaml", "component/nginx/info.yaml"}
+	if diff := cmp.Diff(want, changed); diff != "" {
+		t.Fatalf("failed to update files:\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "component", "nginx")); !os.IsNotExist(err) {
+		t.Fatalf("stale component directory was not removed: %v", err)
+	}
+}
+
+func TestWriteDirKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "component", "docs", "README.md")
+	if _, err := WriteFile(other, []byte("# Docs\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := WriteDir(dir, map[string][]byte{CatalogInfoFile: []byte("test\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("unrelated file was removed: %v", err)
+	}
+}
+
+func newComponent(name, owner string) backstage.Component {
+	return backstage.Component{
+		APIVersion: backstage.APIVersion,
+		Kind:       backstage.KindComponent,
+		Metadata:   backstage.BackstageMetadata{Name: name},
+		Spec:       backstage.ComponentSpec{Owner: owner},
+	}
+}
+
+func stringValues(m map[string][]byte) map[string]string {
+	result := map[string]string{}
+	for k, v := range m {
+		result[k] = string(v)
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+// Package fixture records the metadata of the resources that components are
+// discovered from to a file, so that the catalog can be served from the file
+// without a cluster e.g. for demos, developing Backstage plugins and
+// reproducing bugs.
+package fixture
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+// ignoredAnnotations are not recorded, they can be large and may contain
+// the complete resource.
+var ignoredAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// Record returns the objects in the list with only their type, name,
+// namespace, labels and annotations, which is all that components are
+// discovered from.
+func Record(scheme *runtime.Scheme, list runtime.Object) ([]unstructured.Unstructured, error) {
+	result := []unstructured.Unstructured{}
+	err := meta.EachListItem(list, func(obj runtime.Object) error {
+		gvk, err := apiutil.GVKForObject(obj, scheme)
+		if err != nil {
+			return err
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+
+		var u unstructured.Unstructured
+		u.SetGroupVersionKind(gvk)
+		u.SetName(m.GetName())
+		u.SetNamespace(m.GetNamespace())
+		u.SetLabels(m.GetLabels())
+		annotations := maps.Clone(m.GetAnnotations())
+		for _, v := range ignoredAnnotations {
+			delete(annotations, v)
+		}
+		u.SetAnnotations(annotations)
+		result = append(result, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record objects: %w", err)
+	}
+
+	slices.SortFunc(result, func(a, b unstructured.Unstructured) int {
+		return cmp.Or(
+			strings.Compare(a.GetKind(), b.GetKind()),
+			strings.Compare(a.GetNamespace(), b.GetNamespace()),
+			strings.Compare(a.GetName(), b.GetName()),
+		)
+	})
+	return result, nil
+}
+
+// Write writes the objects to w as a List, so that the fixture can also be
+// read as manifests.
+func Write(w io.Writer, objs []unstructured.Unstructured) error {
+	items := []map[string]any{}
+	for _, v := range objs {
+		items = append(items, v.Object)
+	}
+	list := map[string]any{
+		"apiVersion": metav1.SchemeGroupVersion.Version,
+		"kind":       "List",
+		"items":      items,
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(list); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return enc.Close()
+}
+
+// Read reads the objects from a fixture file.
+func Read(filename string) ([]unstructured.Unstructured, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture: %w", err)
+	}
+	defer f.Close()
+
+	objs, err := manifests.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %w", filename, err)
+	}
+	return objs, nil
+}
+
+// NewClient creates and returns a fake client that serves the objects, the
+// scheme must include the types of the objects.
+//
+// The objects can be listed with field selectors for their name and
+// namespace.
+func NewClient(scheme *runtime.Scheme, objs []unstructured.Unstructured) (client.WithWatch, error) {
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	indexed := map[schema.GroupVersionKind]bool{}
+	for _, v := range objs {
+		gvk := v.GroupVersionKind()
+		typed, err := scheme.New(gvk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s %s: %w", v.GetKind(), client.ObjectKeyFromObject(&v), err)
+		}
+		obj, ok := typed.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("failed to load %s %s: not an object", v.GetKind(), client.ObjectKeyFromObject(&v))
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(v.Object, obj); err != nil {
+			return nil, fmt.Errorf("failed to load %s %s: %w", v.GetKind(), client.ObjectKeyFromObject(&v), err)
+		}
+		builder = builder.WithObjects(obj)
+
+		if indexed[gvk] {
+			continue
+		}
+		indexed[gvk] = true
+		builder = builder.
+			WithIndex(obj, "metadata.name", func(o client.Object) []string {
+				return []string{o.GetName()}
+			}).
+			WithIndex(obj, "metadata.namespace", func(o client.Object) []string {
+				return []string{o.GetNamespace()}
+			})
+	}
+	return builder.Build(), nil
+}
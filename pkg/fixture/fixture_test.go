@@ -0,0 +1,152 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testFixture = `apiVersion: v1
+items:
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      annotations:
+        backstage.io/kubernetes-lifecycle: production
+      labels:
+        app.kubernetes.io/name: mysql
+      name: mysql
+      namespace: team-a
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      labels:
+        app.kubernetes.io/name: nginx
+      name: nginx
+      namespace: team-b
+kind: List
+`
+
+func TestRecord(t *testing.T) {
+	list := &appsv1.DeploymentList{
+		Items: []appsv1.Deployment{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "nginx",
+					Namespace:         "team-b",
+					UID:               "3a4b3c1e-0f3c-4b4e-9a59-8d1b1f0e6f61",
+					CreationTimestamp: metav1.Now(),
+					Labels:            map[string]string{"app.kubernetes.io/name": "nginx"},
+				},
+				Spec: appsv1.DeploymentSpec{MinReadySeconds: 10},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysql",
+					Namespace: "team-a",
+					Labels:    map[string]string{"app.kubernetes.io/name": "mysql"},
+					Annotations: map[string]string{
+						"backstage.io/kubernetes-lifecycle":                "production",
+						"kubectl.kubernetes.io/last-applied-configuration": "{}",
+					},
+				},
+			},
+		},
+	}
+
+	objs, err := Record(testScheme(t), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, objs); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(testFixture, buf.String()); diff != "" {
+		t.Fatalf("failed to record fixture:\n%s", diff)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	objs, err := Read(writeFixture(t, testFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(testScheme(t), objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listTests := []struct {
+		name string
+		opts []client.ListOption
+		want []string
+	}{
+		{name: "all", want: []string{"team-a/mysql", "team-b/nginx"}},
+		{name: "namespace", opts: []client.ListOption{client.InNamespace("team-b")}, want: []string{"team-b/nginx"}},
+		{name: "label selector", opts: []client.ListOption{client.MatchingLabels{"app.kubernetes.io/name": "mysql"}}, want: []string{"team-a/mysql"}},
+		{name: "field selector", opts: []client.ListOption{client.MatchingFieldsSelector{Selector: fields.OneTermEqualSelector("metadata.name", "nginx")}}, want: []string{"team-b/nginx"}},
+	}
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list appsv1.DeploymentList
+			if err := c.List(context.TODO(), &list, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, v := range list.Items {
+				got = append(got, v.Namespace+"/"+v.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to list:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClientWithUnknownKind(t *testing.T) {
+	objs, err := Read(writeFixture(t, `apiVersion: v1
+kind: List
+items:
+  - apiVersion: example.com/v1
+    kind: Widget
+    metadata:
+      name: test
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewClient(testScheme(t), objs); err == nil {
+		t.Fatal("expected an error loading an unknown kind")
+	}
+}
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func writeFixture(t *testing.T, data string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "fixture.yaml")
+	if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
@@ -1,90 +1,470 @@
 package httpapi
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/julienschmidt/httprouter"
 	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/bigkevmcd/peanut-backstage/pkg/auth"
 	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/metrics"
 )
 
 // BackstageRouter is an HTTP API for generating Backstage data from appropriately
 // annotated resources.
 type BackstageRouter struct {
 	*httprouter.Router
-	logger logr.Logger
-	client client.Client
+	logger         logr.Logger
+	clusters       []catalog.Cluster
+	duplicates     catalog.DuplicatePolicy
+	exportSelector labels.Selector
+	namespaces     []string
+	changes        *catalog.ChangeLog
+	synced         func() bool
+	authenticator  auth.Authenticator
+	authorizer     auth.Authorizer
+	metrics        *metrics.Metrics
+	readyChecks    []ReadinessCheck
+	snapshots      *snapshotCache
+	overlays       *catalog.Overlays
+	locationName   string
+	locationDesc   string
+	shutdown       chan struct{}
+	shutdownOnce   sync.Once
+	handler        http.Handler
+}
+
+// The defaults for the root Location.
+const (
+	DefaultLocationName        = "peanut-backstage"
+	DefaultLocationDescription = "Components discovered by peanut-backstage"
+)
+
+// RouterOption configures optional behaviour of the BackstageRouter.
+type RouterOption func(*BackstageRouter)
+
+// WithExportSelector restricts the resources that are published to those
+// that match the selector, e.g. "backstage.gitops.pro/export=true".
+//
+// This is applied in addition to any selectors provided by the caller.
+func WithExportSelector(sel labels.Selector) RouterOption {
+	return func(a *BackstageRouter) {
+		a.exportSelector = sel
+	}
+}
+
+// WithNamespaces restricts the resources that are published to those in the
+// namespaces, resources are listed in each namespace so that permissions are
+// only needed in these namespaces.
+func WithNamespaces(namespaces ...string) RouterOption {
+	return func(a *BackstageRouter) {
+		a.namespaces = namespaces
+	}
+}
+
+// WithClusters discovers components from each of the clusters instead of
+// the router's client, components with the same name in more than one
+// cluster are combined according to the DuplicatePolicy.
+//
+// If some of the clusters can't be listed, the components from the other
+// clusters are served, and the unavailable clusters are listed in the
+// X-Catalog-Unavailable-Clusters header.
+func WithClusters(duplicates catalog.DuplicatePolicy, clusters ...catalog.Cluster) RouterOption {
+	return func(a *BackstageRouter) {
+		a.duplicates = duplicates
+		a.clusters = clusters
+	}
+}
+
+// WithChangeLog enables the delta endpoint, serving the changes recorded in
+// the ChangeLog.
+func WithChangeLog(cl *catalog.ChangeLog) RouterOption {
+	return func(a *BackstageRouter) {
+		a.changes = cl
+	}
+}
+
+// WithRestoredChangeLog serves the catalog from the ChangeLog until synced
+// returns true, for when the ChangeLog has been restored from a snapshot and
+// the cluster may not be reachable yet.
+//
+// The namespaces and labels of the components in the ChangeLog aren't
+// known, so requests filtered by namespace or selectors, and authorized
+// requests, are always served from the clusters.
+func WithRestoredChangeLog(cl *catalog.ChangeLog, synced func() bool) RouterOption {
+	return func(a *BackstageRouter) {
+		a.changes = cl
+		a.synced = synced
+	}
+}
+
+// WithAuthenticator requires that requests are authenticated, other than
+// the health endpoints.
+func WithAuthenticator(au auth.Authenticator) RouterOption {
+	return func(a *BackstageRouter) {
+		a.authenticator = au
+	}
+}
+
+// WithAuthorizer restricts the components that each User can see to those
+// discovered from namespaces that the Authorizer allows.
+//
+// This requires an Authenticator to identify the User.
+func WithAuthorizer(az auth.Authorizer) RouterOption {
+	return func(a *BackstageRouter) {
+		a.authorizer = az
+	}
+}
+
+// WithMetrics records metrics for requests, and serves them from /metrics.
+func WithMetrics(m *metrics.Metrics) RouterOption {
+	return func(a *BackstageRouter) {
+		a.metrics = m
+	}
+}
+
+// WithReadinessCheck adds a check that must pass for the /readyz endpoint to
+// report that the server is ready.
+func WithReadinessCheck(name string, check func(context.Context) error) RouterOption {
+	return func(a *BackstageRouter) {
+		a.readyChecks = append(a.readyChecks, ReadinessCheck{Name: name, Check: check})
+	}
+}
+
+// WithMaxStaleness serves the last successfully listed resources when
+// listing fails, for up to the provided duration after they were listed.
+//
+// Responses that are served from a snapshot have the X-Catalog-Stale header
+// set, and the age of the snapshot in the Age header.
+func WithMaxStaleness(d time.Duration) RouterOption {
+	return func(a *BackstageRouter) {
+		if d > 0 {
+			a.snapshots = newSnapshotCache(d)
+		}
+	}
+}
+
+// WithOverlays merges the overlays onto the discovered components before
+// they are served.
+func WithOverlays(o *catalog.Overlays) RouterOption {
+	return func(a *BackstageRouter) {
+		a.overlays = o
+	}
+}
+
+// WithRootLocation sets the name and description of the root Location
+// served from /backstage/catalog-info.yaml.
+func WithRootLocation(name, description string) RouterOption {
+	return func(a *BackstageRouter) {
+		a.locationName = name
+		a.locationDesc = description
+	}
 }
 
 // NewRouter creates and returns a new Backstage router ready for use.
-func NewRouter(l logr.Logger, c client.Client) *BackstageRouter {
+func NewRouter(l logr.Logger, c client.Client, opts ...RouterOption) *BackstageRouter {
 	api := &BackstageRouter{
-		Router: httprouter.New(),
-		logger: l,
-		client: c,
+		Router:         httprouter.New(),
+		logger:         l,
+		clusters:       []catalog.Cluster{{Client: c}},
+		duplicates:     catalog.MergeDuplicates,
+		exportSelector: labels.Everything(),
+		locationName:   DefaultLocationName,
+		locationDesc:   DefaultLocationDescription,
+		shutdown:       make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(api)
+	}
+	api.handle(http.MethodGet, "/backstage/catalog-info.yaml", api.handleCatalogInfo)
+	api.handle(http.MethodGet, "/backstage/component/:name/info.yaml", api.handleComponent)
+
+	api.handle(http.MethodGet, "/backstage/namespaces/:ns/catalog-info.yaml", api.handleCatalogInfo)
+	api.handle(http.MethodGet, "/backstage/namespaces/:ns/component/:name/info.yaml", api.handleComponent)
+	api.handle(http.MethodGet, "/backstage/owners/:owner/catalog-info.yaml", api.handleCatalogInfo)
+	api.handle(http.MethodGet, "/backstage/owners/:owner/component/:name/info.yaml", api.handleComponent)
+	api.handle(http.MethodGet, "/backstage/systems/:system/catalog-info.yaml", api.handleCatalogInfo)
+	api.handle(http.MethodGet, "/backstage/systems/:system/component/:name/info.yaml", api.handleComponent)
+
+	api.handle(http.MethodGet, "/backstage/entities", api.handleEntities)
+	// The change log isn't partitioned by namespace, so it can't be
+	// filtered per User.
+	if api.changes != nil {
+		if api.authorizer == nil {
+			api.handle(http.MethodGet, "/backstage/delta", api.handleDelta)
+			api.handle(http.MethodGet, "/backstage/events", api.handleEvents)
+		} else {
+			l.Info("the delta and events endpoints are disabled because the change log can't be filtered by the authorizer")
+		}
+	}
+
+	api.handle(http.MethodGet, "/healthz", handleHealthz)
+	api.handle(http.MethodGet, "/readyz", api.handleReadyz)
+	if api.metrics != nil {
+		api.Handler(http.MethodGet, "/metrics", api.metrics.Handler())
+	}
+	api.NotFound = http.HandlerFunc(handleNotFound)
+	api.MethodNotAllowed = http.HandlerFunc(handleMethodNotAllowed)
+
+	api.handler = api.Router
+	if api.authenticator != nil {
+		api.handler = api.authenticate(api.handler)
 	}
-	api.HandlerFunc(http.MethodGet, "/backstage/catalog-info.yaml", api.handleCatalogInfo)
-	api.HandlerFunc(http.MethodGet, "/backstage/component/:name/info.yaml", api.handleComponent)
+	api.handler = api.logRequests(api.handler)
 	return api
 }
 
+// Shutdown stops long-lived requests e.g. event streams, so that the server
+// can shut down gracefully.
+func (a *BackstageRouter) Shutdown() {
+	a.shutdownOnce.Do(func() {
+		close(a.shutdown)
+	})
+}
+
+// handle registers the handler for the method and path, recording metrics
+// for requests with the path as the route.
+func (a *BackstageRouter) handle(method, path string, h http.HandlerFunc) {
+	if a.metrics == nil {
+		a.HandlerFunc(method, path, h)
+		return
+	}
+	a.HandlerFunc(method, path, func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		a.metrics.ObserveRequest(path, method, rec.status, time.Since(start))
+	})
+}
+
+// handleNotFound returns an error response for paths that aren't routed.
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, newNotFoundError(fmt.Sprintf("path %q not found", r.URL.Path)))
+}
+
+// handleMethodNotAllowed returns an error response for methods that aren't
+// routed for the path, the router sets the Allow header.
+func handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, &apiError{
+		status:  http.StatusMethodNotAllowed,
+		name:    inputError,
+		message: fmt.Sprintf("method %s is not allowed for path %q", r.Method, r.URL.Path),
+	})
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (a *BackstageRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.handler.ServeHTTP(w, r)
+}
+
 func (a *BackstageRouter) handleComponent(w http.ResponseWriter, r *http.Request) {
-	params := httprouter.ParamsFromContext(r.Context())
-	name := params.ByName("name")
-	a.logger.Info("querying component", "component", name, "path", r.URL.String())
+	name := httprouter.ParamsFromContext(r.Context()).ByName("name")
+	a.loggerFrom(r.Context()).V(1).Info("querying component", "component", name, "path", r.URL.String())
 
-	var deploymentList appsv1.DeploymentList
-	if err := a.client.List(r.Context(), &deploymentList); err != nil {
-		http.Error(w, "failed to load deployments", http.StatusInternalServerError)
+	scope, err := scopeFromRequest(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	parser := backstage.NewComponentParser()
-	if err := parser.Add(&deploymentList); err != nil {
-		http.Error(w, "failed to parse deployments", http.StatusInternalServerError)
+	components, err := a.components(r.Context(), scope)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	for _, v := range parser.Components() {
+	for _, v := range components {
 		if v.Metadata.Name == name {
-			marshalResponse(w, v)
+			marshalResponse(w, r, v)
 			return
 		}
 	}
-	http.NotFound(w, r)
+	writeError(w, r, newNotFoundError(fmt.Sprintf("component %q not found", name)))
 }
 
 func (a *BackstageRouter) handleCatalogInfo(w http.ResponseWriter, r *http.Request) {
-	a.logger.Info("querying catalog-info.yaml")
-	var deploymentList appsv1.DeploymentList
-	if err := a.client.List(r.Context(), &deploymentList); err != nil {
-		http.Error(w, "failed to load deployments", http.StatusInternalServerError)
+	a.loggerFrom(r.Context()).V(1).Info("querying catalog-info.yaml", "path", r.URL.String())
+	scope, err := scopeFromRequest(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	parser := backstage.NewComponentParser()
-	if err := parser.Add(&deploymentList); err != nil {
-		http.Error(w, "failed to parse deployments", http.StatusInternalServerError)
+	components, err := a.components(r.Context(), scope)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	query := selectorQuery(r)
 	targets := []string{}
-	for _, v := range parser.Components() {
-		targets = append(targets, fmt.Sprintf("./component/%s/info.yaml", v.Metadata.Name))
+	for _, v := range components {
+		targets = append(targets, fmt.Sprintf("./component/%s/info.yaml%s", v.Metadata.Name, query))
+	}
+	name, description := scope.location(a.locationName, a.locationDesc)
+	marshalResponse(w, r, backstage.NewLocation(name, description, targets...))
+}
+
+// components lists the resources in the scope and parses them into
+// Components, dropping any that fall outside of the scope.
+//
+// If an authorizer is configured, only resources in namespaces that the
+// User in the context is authorized for are parsed.
+func (a *BackstageRouter) components(ctx context.Context, s scope) ([]backstage.Component, error) {
+	if a.servesRestored(s) {
+		a.loggerFrom(ctx).V(1).Info("serving components from the restored change log")
+		entities, _ := a.changes.Snapshot()
+		result := []backstage.Component{}
+		for _, v := range entities {
+			if s.matches(v) {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	}
+
+	tombstones := a.tombstones(s)
+	filtered := s.filtered()
+	s = s.withSelector(a.exportSelector)
+
+	lists, err := a.list(ctx, s, filtered)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := []catalog.ClusterComponents{}
+	for _, l := range lists {
+		if a.authorizer != nil {
+			items, err := a.authorizedItems(ctx, l.List.Items)
+			if err != nil {
+				return nil, err
+			}
+			l.List.Items = items
+		}
+
+		start := time.Now()
+		components, err := catalog.Parse(l.List)
+		a.metrics.ObserveParse(time.Since(start))
+		if err != nil {
+			a.metrics.Diagnostic(catalog.ReasonParseFailed)
+			return nil, fmt.Errorf("failed to parse components: %w", err)
+		}
+		discovered = append(discovered, catalog.ClusterComponents{Cluster: l.Cluster, Components: components})
+	}
+	components := a.overlays.Apply(catalog.Combine(a.duplicates, discovered))
+
+	result := []backstage.Component{}
+	for _, v := range components {
+		if s.matches(v) {
+			result = append(result, v)
+		}
+	}
+	if len(tombstones) > 0 {
+		for _, v := range tombstones {
+			if s.matches(v) && !slices.ContainsFunc(result, func(c backstage.Component) bool { return c.Metadata.Name == v.Metadata.Name }) {
+				result = append(result, v)
+			}
+		}
+		slices.SortFunc(result, func(a, b backstage.Component) int {
+			return strings.Compare(a.Metadata.Name, b.Metadata.Name)
+		})
+	}
+
+	return result, nil
+}
+
+// servesRestored returns true if the scope is served from the restored
+// ChangeLog because the clusters haven't been synced yet.
+func (a *BackstageRouter) servesRestored(s scope) bool {
+	return a.synced != nil && a.authorizer == nil && !s.filtered() && !a.synced()
+}
+
+// tombstones returns the components that are being kept during the grace
+// period after their resources were removed.
+//
+// The namespace and labels of removed resources aren't known, so these are
+// only included when the scope isn't restricted by namespace, selectors or
+// authorization.
+func (a *BackstageRouter) tombstones(s scope) []backstage.Component {
+	if a.changes == nil || a.authorizer != nil || s.filtered() {
+		return nil
+	}
+	return a.changes.Tombstones()
+}
+
+// list lists the resources in the scope from each cluster, falling back to
+// the resources in the scope from the last good unfiltered list for the
+// cluster if listing fails and stale responses are enabled.
+//
+// Snapshots are only taken when the scope isn't filtered by the caller.
+// Clusters that can't be listed are left out, an error is only returned if
+// none of the clusters could be listed.
+func (a *BackstageRouter) list(ctx context.Context, s scope, filtered bool) ([]catalog.ClusterList, error) {
+	namespaces := a.namespaces
+	if s.namespace != "" {
+		if len(namespaces) > 0 && !slices.Contains(namespaces, s.namespace) {
+			return nil, nil
+		}
+		namespaces = nil
+	}
+
+	result := []catalog.ClusterList{}
+	unavailable := []string{}
+	var lastErr error
+	for _, l := range catalog.ListClusters(ctx, a.clusters, namespaces, s.listOptions()...) {
+		// The availability of the clusters is recorded by the Refresher, so
+		// that requests don't overwrite it.
+		if l.Err == nil {
+			if a.snapshots != nil && !filtered {
+				a.snapshots.put(l.Cluster, l.List.Items)
+			}
+			result = append(result, l)
+			continue
+		}
+
+		a.metrics.ListError("deployments")
+		if l.Cluster != "" {
+			a.metrics.Diagnostic(catalog.ReasonClusterUnavailable)
+		} else {
+			a.metrics.Diagnostic(catalog.ReasonListFailed)
+		}
+		if a.snapshots != nil {
+			if items, age, ok := a.snapshots.get(l.Cluster, s); ok {
+				a.loggerFrom(ctx).Error(l.Err, "serving stale components", "age", age, "cluster", l.Cluster)
+				markStale(ctx, age)
+				result = append(result, catalog.ClusterList{Cluster: l.Cluster, List: &appsv1.DeploymentList{Items: items}})
+				continue
+			}
+		}
+		lastErr = l.Err
+		if l.Cluster != "" {
+			a.loggerFrom(ctx).Error(l.Err, "cluster unavailable", "cluster", l.Cluster)
+			unavailable = append(unavailable, l.Cluster)
+		}
+	}
+	if len(result) == 0 && lastErr != nil {
+		return nil, kubernetesError("failed to load components", lastErr)
 	}
-	// TODO: How to configure name, description for catalog-info?
-	marshalResponse(w, backstage.NewLocation("test-service", "just a test", targets...))
+	markUnavailable(ctx, unavailable)
+	return result, nil
 }
 
-func marshalResponse(w http.ResponseWriter, v interface{}) {
+func marshalResponse(w http.ResponseWriter, r *http.Request, v interface{}) {
+	writeStaleHeaders(w, r)
 	w.Header().Set("Content-Type", "application/yaml")
 	if err := yaml.NewEncoder(w).Encode(v); err != nil {
-		log.Printf("failed to encode response: %s", err)
+		logr.FromContextOrDiscard(r.Context()).Error(err, "failed to encode response")
 	}
 }
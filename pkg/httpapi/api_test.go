@@ -2,22 +2,30 @@ package httpapi
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-logr/zapr"
 	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
 	"github.com/bigkevmcd/peanut-backstage/test"
 )
 
@@ -26,6 +34,7 @@ const (
 	nameLabel      = "app.kubernetes.io/name"
 	componentLabel = "app.kubernetes.io/component"
 	createdByLabel = "app.kubernetes.io/created-by"
+	partOfLabel    = "app.kubernetes.io/part-of"
 )
 
 func TestGetRootLocation(t *testing.T) {
@@ -49,8 +58,8 @@ func TestGetRootLocation(t *testing.T) {
 		"apiVersion": "backstage.io/v1alpha1",
 		"kind":       "Location",
 		"metadata": map[string]interface{}{
-			"name":        "test-service",
-			"description": "just a test",
+			"name":        DefaultLocationName,
+			"description": DefaultLocationDescription,
 		},
 		"spec": map[string]interface{}{
 			"targets": []any{
@@ -60,6 +69,17 @@ func TestGetRootLocation(t *testing.T) {
 	})
 }
 
+func TestGetRootLocationWithName(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t), WithRootLocation("production", "Production components"))
+	req := makeClientRequest(t, ts, "/backstage/catalog-info.yaml")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertYAMLResponse(t, res, scopedLocation("production", "Production components"))
+}
+
 func TestGetComponent(t *testing.T) {
 	dep := test.NewDeployment("test", "test-ns",
 		test.WithLabels(map[string]string{
@@ -97,8 +117,456 @@ func TestGetComponent(t *testing.T) {
 	})
 }
 
-func newTestServer(t *testing.T, c client.Client) *httptest.Server {
-	router := NewRouter(zapr.NewLogger(zap.NewNop()), c)
+func TestGetScopedLocation(t *testing.T) {
+	mysql := test.NewDeployment("mysql", "team-a",
+		test.WithLabels(map[string]string{
+			nameLabel:      "mysql",
+			createdByLabel: "team-a",
+			partOfLabel:    "user-db",
+		}),
+	)
+	nginx := test.NewDeployment("nginx", "team-b",
+		test.WithLabels(map[string]string{
+			nameLabel:      "nginx",
+			createdByLabel: "team-b",
+			partOfLabel:    "frontend",
+		}),
+	)
+	ts := newTestServer(t, newFakeClient(t, &mysql, &nginx))
+
+	scopeTests := []struct {
+		path string
+		want map[string]interface{}
+	}{
+		{
+			path: "/backstage/namespaces/team-a/catalog-info.yaml",
+			want: scopedLocation("namespace-team-a", "Components in namespace team-a", "./component/mysql/info.yaml"),
+		},
+		{
+			path: "/backstage/owners/team-b/catalog-info.yaml",
+			want: scopedLocation("owner-team-b", "Components owned by team-b", "./component/nginx/info.yaml"),
+		},
+		{
+			path: "/backstage/systems/user-db/catalog-info.yaml",
+			want: scopedLocation("system-user-db", "Components in system user-db", "./component/mysql/info.yaml"),
+		},
+		{
+			path: "/backstage/systems/unknown/catalog-info.yaml",
+			want: scopedLocation("system-unknown", "Components in system unknown"),
+		},
+	}
+
+	for _, tt := range scopeTests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := makeClientRequest(t, ts, tt.path)
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertYAMLResponse(t, res, tt.want)
+		})
+	}
+}
+
+func TestGetLocationWithNamespaces(t *testing.T) {
+	deps := []appsv1.Deployment{}
+	for _, ns := range []string{"team-a", "team-b", "team-c"} {
+		deps = append(deps, test.NewDeployment("app-"+ns, ns,
+			test.WithLabels(map[string]string{nameLabel: "app-" + ns})))
+	}
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	fc := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&deps[0], &deps[1], &deps[2]).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				listOpts := &client.ListOptions{}
+				listOpts.ApplyOptions(opts)
+				if listOpts.Namespace == "" {
+					return fmt.Errorf("listing in all namespaces is not allowed")
+				}
+				return c.List(ctx, list, opts...)
+			},
+		}).
+		Build()
+	ts := newTestServer(t, fc, WithNamespaces("team-a", "team-b"))
+
+	scopeTests := []struct {
+		path string
+		want map[string]interface{}
+	}{
+		{
+			path: "/backstage/catalog-info.yaml",
+			want: scopedLocation(DefaultLocationName, DefaultLocationDescription,
+				"./component/app-team-a/info.yaml", "./component/app-team-b/info.yaml"),
+		},
+		{
+			path: "/backstage/namespaces/team-b/catalog-info.yaml",
+			want: scopedLocation("namespace-team-b", "Components in namespace team-b", "./component/app-team-b/info.yaml"),
+		},
+		{
+			path: "/backstage/namespaces/team-c/catalog-info.yaml",
+			want: scopedLocation("namespace-team-c", "Components in namespace team-c"),
+		},
+	}
+
+	for _, tt := range scopeTests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := makeClientRequest(t, ts, tt.path)
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertYAMLResponse(t, res, tt.want)
+		})
+	}
+}
+
+func TestGetScopedComponent(t *testing.T) {
+	dep := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel:      "mysql",
+			createdByLabel: "test-team",
+		}),
+	)
+	ts := newTestServer(t, newFakeClient(t, &dep))
+
+	req := makeClientRequest(t, ts, "/backstage/owners/test-team/component/mysql/info.yaml")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, map[string]interface{}{
+		"apiVersion": "backstage.io/v1alpha1",
+		"kind":       "Component",
+		"metadata": map[string]interface{}{
+			"name": "mysql",
+		},
+		"spec": map[string]interface{}{
+			"lifecycle": "",
+			"owner":     "test-team",
+			"type":      "",
+			"system":    "",
+		},
+	})
+
+	req = makeClientRequest(t, ts, "/backstage/namespaces/other-ns/component/mysql/info.yaml")
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestGetLocationWithSelectors(t *testing.T) {
+	mysql := test.NewDeployment("mysql", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel: "mysql",
+			"tier":    "backend",
+		}),
+	)
+	nginx := test.NewDeployment("nginx", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel:             "nginx",
+			"tier":                "frontend",
+			backstage.ExportLabel: "true",
+		}),
+	)
+	fc := newFakeClient(t, &mysql, &nginx)
+
+	selectorTests := []struct {
+		name string
+		opts []RouterOption
+		path string
+		want map[string]interface{}
+	}{
+		{
+			name: "label selector",
+			path: "/backstage/catalog-info.yaml?labelSelector=tier%3Dbackend",
+			want: scopedLocation(DefaultLocationName, DefaultLocationDescription, "./component/mysql/info.yaml?labelSelector=tier%3Dbackend"),
+		},
+		{
+			name: "field selector",
+			path: "/backstage/catalog-info.yaml?fieldSelector=metadata.name%3Dnginx",
+			want: scopedLocation(DefaultLocationName, DefaultLocationDescription, "./component/nginx/info.yaml?fieldSelector=metadata.name%3Dnginx"),
+		},
+		{
+			name: "export selector",
+			opts: []RouterOption{WithExportSelector(labels.SelectorFromSet(labels.Set{backstage.ExportLabel: "true"}))},
+			path: "/backstage/catalog-info.yaml",
+			want: scopedLocation(DefaultLocationName, DefaultLocationDescription, "./component/nginx/info.yaml"),
+		},
+		{
+			name: "export selector and label selector",
+			opts: []RouterOption{WithExportSelector(labels.SelectorFromSet(labels.Set{backstage.ExportLabel: "true"}))},
+			path: "/backstage/catalog-info.yaml?labelSelector=tier%3Dbackend",
+			want: scopedLocation(DefaultLocationName, DefaultLocationDescription),
+		},
+	}
+
+	for _, tt := range selectorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, fc, tt.opts...)
+			req := makeClientRequest(t, ts, tt.path)
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertYAMLResponse(t, res, tt.want)
+		})
+	}
+}
+
+func TestGetComponentWithExportSelector(t *testing.T) {
+	dep := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel: "mysql",
+		}),
+	)
+	ts := newTestServer(t, newFakeClient(t, &dep),
+		WithExportSelector(labels.SelectorFromSet(labels.Set{backstage.ExportLabel: "true"})))
+
+	req := makeClientRequest(t, ts, "/backstage/component/mysql/info.yaml")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestGetComponentWithOverlays(t *testing.T) {
+	dep := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel:      "mysql",
+			componentLabel: "database",
+			createdByLabel: "test-team",
+		}),
+	)
+	overlays := catalog.NewOverlays([]backstage.Component{
+		{
+			Metadata: backstage.BackstageMetadata{
+				Name:        "mysql",
+				Description: "The user database",
+				Links:       []backstage.Link{{URL: "https://example.com/runbook", Title: "Runbook"}},
+			},
+			Spec: backstage.ComponentSpec{System: "users"},
+		},
+	})
+	ts := newTestServer(t, newFakeClient(t, &dep), WithOverlays(overlays))
+
+	// Overlays are applied before components are filtered by system.
+	req := makeClientRequest(t, ts, "/backstage/systems/users/component/mysql/info.yaml")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertYAMLResponse(t, res, map[string]interface{}{
+		"apiVersion": "backstage.io/v1alpha1",
+		"kind":       "Component",
+		"metadata": map[string]interface{}{
+			"name":        "mysql",
+			"description": "The user database",
+			"links": []any{
+				map[string]any{"url": "https://example.com/runbook", "title": "Runbook"},
+			},
+		},
+		"spec": map[string]interface{}{
+			"lifecycle": "",
+			"owner":     "test-team",
+			"type":      "database",
+			"system":    "users",
+		},
+	})
+}
+
+func TestGetLocationWithInvalidSelector(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t))
+
+	req := makeClientRequest(t, ts, "/backstage/catalog-info.yaml?labelSelector=tier%3D%3D%3D")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestGetLocationWithTombstones(t *testing.T) {
+	dep := test.NewDeployment("mysql", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "mysql"}),
+	)
+	cl := catalog.NewChangeLog(10, catalog.WithGracePeriod(time.Minute))
+	cl.Update([]backstage.Component{newComponent("mysql", ""), newComponent("nginx", "team-a")})
+	cl.Update([]backstage.Component{newComponent("mysql", "")})
+	ts := newTestServer(t, newFakeClient(t, &dep), WithChangeLog(cl))
+
+	req := makeClientRequest(t, ts, "/backstage/catalog-info.yaml")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, scopedLocation(DefaultLocationName, DefaultLocationDescription,
+		"./component/mysql/info.yaml", "./component/nginx/info.yaml"))
+
+	req = makeClientRequest(t, ts, "/backstage/owners/team-a/component/nginx/info.yaml")
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, componentMap("nginx", "team-a"))
+
+	// The namespace of the removed resources isn't known.
+	req = makeClientRequest(t, ts, "/backstage/namespaces/test-ns/catalog-info.yaml")
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, scopedLocation("namespace-test-ns", "Components in namespace test-ns",
+		"./component/mysql/info.yaml"))
+}
+
+func TestGetLocationFromRestoredChangeLog(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	cl.Restore([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-b")}, 5)
+	var synced atomic.Bool
+	ts := newTestServer(t, newErrorClient(t, apierrors.NewServiceUnavailable("etcd is unavailable")),
+		WithRestoredChangeLog(cl, synced.Load))
+
+	req := makeClientRequest(t, ts, "/backstage/catalog-info.yaml")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, scopedLocation(DefaultLocationName, DefaultLocationDescription,
+		"./component/mysql/info.yaml", "./component/nginx/info.yaml"))
+
+	req = makeClientRequest(t, ts, "/backstage/owners/team-b/component/nginx/info.yaml")
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, componentMap("nginx", "team-b"))
+
+	// The namespaces of the restored components aren't known.
+	for _, path := range []string{"/backstage/namespaces/test-ns/catalog-info.yaml", "/backstage/catalog-info.yaml?labelSelector=tier%3Dbackend"} {
+		res, err = ts.Client().Do(makeClientRequest(t, ts, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s got status %v, want %v", path, res.StatusCode, http.StatusServiceUnavailable)
+		}
+	}
+
+	synced.Store(true)
+	res, err = ts.Client().Do(makeClientRequest(t, ts, "/backstage/catalog-info.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got status %v after syncing, want %v", res.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestGetLocationWithClusters(t *testing.T) {
+	dev := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "mysql", createdByLabel: "team-a"}),
+	)
+	staging := test.NewDeployment("test", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "nginx", createdByLabel: "team-b"}),
+	)
+	failing := interceptor.NewClient(newFakeClient(t, &staging).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return fmt.Errorf("cluster is unavailable")
+		},
+	})
+	ts := newTestServer(t, newFakeClient(t), WithClusters(catalog.MergeDuplicates,
+		catalog.Cluster{Name: "dev", Client: newFakeClient(t, &dev)},
+		catalog.Cluster{Name: "staging", Client: failing},
+	))
+
+	res, err := ts.Client().Do(makeClientRequest(t, ts, "/backstage/catalog-info.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := res.Header.Get(unavailableHeader); h != "staging" {
+		t.Errorf("got %s %q, want %q", unavailableHeader, h, "staging")
+	}
+	assertYAMLResponse(t, res, scopedLocation(DefaultLocationName, DefaultLocationDescription,
+		"./component/mysql/info.yaml"))
+
+	res, err = ts.Client().Do(makeClientRequest(t, ts, "/backstage/component/mysql/info.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, map[string]interface{}{
+		"apiVersion": "backstage.io/v1alpha1",
+		"kind":       "Component",
+		"metadata": map[string]interface{}{
+			"name":        "mysql",
+			"annotations": map[string]any{backstage.ClusterAnnotation: "dev"},
+		},
+		"spec": map[string]interface{}{
+			"lifecycle": "",
+			"owner":     "team-a",
+			"type":      "",
+			"system":    "",
+		},
+	})
+}
+
+func TestGetLocationWithUnavailableClusters(t *testing.T) {
+	failing := interceptor.NewClient(newFakeClient(t).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return apierrors.NewServiceUnavailable("cluster is unavailable")
+		},
+	})
+	ts := newTestServer(t, newFakeClient(t), WithClusters(catalog.MergeDuplicates,
+		catalog.Cluster{Name: "dev", Client: failing},
+		catalog.Cluster{Name: "staging", Client: failing},
+	))
+
+	res, err := ts.Client().Do(makeClientRequest(t, ts, "/backstage/catalog-info.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestGetLocationFromFixture(t *testing.T) {
+	ts := newTestServer(t, test.NewFixtureClient(t, "testdata/fixture.yaml"))
+
+	res, err := ts.Client().Do(makeClientRequest(t, ts, "/backstage/systems/user-system/catalog-info.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertYAMLResponse(t, res, scopedLocation("system-user-system", "Components in system user-system",
+		"./component/mysql/info.yaml", "./component/nginx/info.yaml"))
+}
+
+func newTestServer(t *testing.T, c client.Client, opts ...RouterOption) *httptest.Server {
+	router := NewRouter(zapr.NewLogger(zap.NewNop()), c, opts...)
 	ts := httptest.NewTLSServer(router)
 	t.Cleanup(ts.Close)
 	return ts
@@ -147,8 +615,47 @@ func newFakeClient(t *testing.T, objs ...runtime.Object) client.Client {
 		t.Fatal(err)
 	}
 
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
 	return fake.NewClientBuilder().
 		WithScheme(scheme).
 		WithRuntimeObjects(objs...).
+		WithIndex(&appsv1.Deployment{}, "metadata.name", func(o client.Object) []string {
+			return []string{o.GetName()}
+		}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				// alice can list deployments in team-a, and bob in
+				// team-c.
+				allowed := map[string]string{"alice": "team-a", "bob": "team-c"}
+				review.Status.Allowed = allowed[review.Spec.User] == review.Spec.ResourceAttributes.Namespace
+				return nil
+			},
+		}).
 		Build()
 }
+
+func scopedLocation(name, description string, targets ...string) map[string]interface{} {
+	location := map[string]interface{}{
+		"apiVersion": "backstage.io/v1alpha1",
+		"kind":       "Location",
+		"metadata": map[string]interface{}{
+			"name":        name,
+			"description": description,
+		},
+	}
+	if len(targets) > 0 {
+		t := []any{}
+		for _, v := range targets {
+			t = append(t, v)
+		}
+		location["spec"] = map[string]interface{}{"targets": t}
+	}
+	return location
+}
@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/auth"
+)
+
+// errForbidden is returned when the caller is not authorized.
+var errForbidden = &apiError{
+	status:  http.StatusForbidden,
+	name:    notAllowedError,
+	message: "the caller is not authorized",
+}
+
+// maxConcurrentAuthorizations is the maximum number of namespaces that are
+// authorized at once for a request.
+const maxConcurrentAuthorizations = 10
+
+// authorizedItems filters the items to those in namespaces that the User in
+// the context is authorized for.
+//
+// The namespaces are authorized concurrently.
+func (a *BackstageRouter) authorizedItems(ctx context.Context, items []appsv1.Deployment) ([]appsv1.Deployment, error) {
+	user, ok := auth.UserFrom(ctx)
+	if !ok {
+		return nil, errForbidden
+	}
+
+	namespaces := []string{}
+	for _, v := range items {
+		if !slices.Contains(namespaces, v.Namespace) {
+			namespaces = append(namespaces, v.Namespace)
+		}
+	}
+	allowed := make([]bool, len(namespaces))
+	errs := make([]error, len(namespaces))
+	limit := make(chan struct{}, maxConcurrentAuthorizations)
+	var wg sync.WaitGroup
+	for i, ns := range namespaces {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+			allowed[i], errs[i] = a.authorizer.Authorized(ctx, user, ns)
+		}()
+	}
+	wg.Wait()
+
+	authorized := map[string]bool{}
+	for i, ns := range namespaces {
+		if errs[i] != nil {
+			a.loggerFrom(ctx).Error(errs[i], "failed to authorize user", "user", user.Name, "namespace", ns)
+			return nil, kubernetesError("failed to authorize request", errs[i])
+		}
+		authorized[ns] = allowed[i]
+	}
+
+	result := []appsv1.Deployment{}
+	for _, v := range items {
+		if authorized[v.Namespace] {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
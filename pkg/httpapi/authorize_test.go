@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/auth"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestAuthorization(t *testing.T) {
+	mysql := test.NewDeployment("mysql", "team-a",
+		test.WithLabels(map[string]string{nameLabel: "mysql"}),
+	)
+	nginx := test.NewDeployment("nginx", "team-b",
+		test.WithLabels(map[string]string{nameLabel: "nginx"}),
+	)
+	tokens, err := auth.ParseStaticTokens(strings.NewReader("alice-token,alice,1001\nbob-token,bob,1002\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := newFakeClient(t, &mysql, &nginx)
+	authorizer := auth.NewSubjectAccessReview(fc)
+	ts := newTestServer(t, fc, WithAuthenticator(tokens), WithAuthorizer(authorizer))
+
+	authzTests := []struct {
+		token string
+		want  map[string]interface{}
+	}{
+		{"alice-token", scopedLocation(DefaultLocationName, DefaultLocationDescription, "./component/mysql/info.yaml")},
+		{"bob-token", scopedLocation(DefaultLocationName, DefaultLocationDescription)},
+	}
+
+	for _, tt := range authzTests {
+		t.Run(tt.token, func(t *testing.T) {
+			req := makeClientRequest(t, ts, "/backstage/catalog-info.yaml", func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			})
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertYAMLResponse(t, res, tt.want)
+		})
+	}
+}
+
+func TestAuthorizationDisablesChangeLogEndpoints(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{})
+	tokens, err := auth.ParseStaticTokens(strings.NewReader("alice-token,alice,1001\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := newFakeClient(t)
+	router := NewRouter(logger, fc,
+		WithChangeLog(catalog.NewChangeLog(10)),
+		WithAuthenticator(tokens),
+		WithAuthorizer(auth.NewSubjectAccessReview(fc)))
+
+	want := `"msg"="the delta and events endpoints are disabled because the change log can't be filtered by the authorizer"`
+	if !strings.Contains(strings.Join(logs, "\n"), want) {
+		t.Errorf("got logs %q, want %q", logs, want)
+	}
+	for _, path := range []string{"/backstage/delta", "/backstage/events"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer alice-token")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("got status %v, want %v", rec.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
+
+func TestAuthorizedItemsLimitsConcurrency(t *testing.T) {
+	items := []appsv1.Deployment{}
+	for i := range 25 {
+		items = append(items, test.NewDeployment("app", fmt.Sprintf("team-%d", i)))
+	}
+	az := &blockingAuthorizer{allowed: "team-3"}
+	router := NewRouter(logr.Discard(), newFakeClient(t), WithAuthorizer(az))
+	ctx := auth.WithUser(context.Background(), &auth.User{Name: "alice"})
+
+	result, err := router.authorizedItems(ctx, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result) != 1 || result[0].Namespace != "team-3" {
+		t.Errorf("got authorized items %v", result)
+	}
+	if az.calls.Load() != 25 {
+		t.Errorf("got %d calls, want 25", az.calls.Load())
+	}
+	if peak := az.maxInFlight.Load(); peak > maxConcurrentAuthorizations || peak < 2 {
+		t.Errorf("got %d concurrent calls, want between 2 and %d", peak, maxConcurrentAuthorizations)
+	}
+}
+
+// blockingAuthorizer allows a single namespace, and records the number of
+// concurrent calls.
+type blockingAuthorizer struct {
+	allowed     string
+	calls       atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (b *blockingAuthorizer) Authorized(ctx context.Context, u *auth.User, namespace string) (bool, error) {
+	b.calls.Add(1)
+	n := b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+	for {
+		peak := b.maxInFlight.Load()
+		if n <= peak || b.maxInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return namespace == b.allowed, nil
+}
@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+// deltaResponse is returned from the delta endpoint.
+//
+// Without a cursor, Entities contains a full snapshot of the catalog,
+// with a cursor, only the changes since the cursor are returned.
+type deltaResponse struct {
+	Cursor   string                `yaml:"cursor"`
+	Entities []backstage.Component `yaml:"entities,omitempty"`
+	Added    []backstage.Component `yaml:"added,omitempty"`
+	Updated  []backstage.Component `yaml:"updated,omitempty"`
+	Removed  []string              `yaml:"removed,omitempty"`
+}
+
+func (a *BackstageRouter) handleDelta(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("cursor")
+	a.loggerFrom(r.Context()).V(1).Info("querying delta", "cursor", raw)
+	if raw == "" {
+		entities, revision := a.changes.Snapshot()
+		marshalResponse(w, r, deltaResponse{Cursor: formatRevision(revision), Entities: entities})
+		return
+	}
+
+	revision, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		writeError(w, r, newInputError(fmt.Sprintf("invalid cursor %q", raw), err))
+		return
+	}
+
+	delta, current, err := a.changes.Since(revision)
+	if errors.Is(err, catalog.ErrRevisionExpired) {
+		writeError(w, r, &apiError{
+			status:  http.StatusGone,
+			name:    goneError,
+			message: fmt.Sprintf("cursor %q has expired, fetch a new snapshot", raw),
+		})
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	marshalResponse(w, r, deltaResponse{
+		Cursor:  formatRevision(current),
+		Added:   delta.Added,
+		Updated: delta.Updated,
+		Removed: delta.Removed,
+	})
+}
+
+func formatRevision(r uint64) string {
+	return strconv.FormatUint(r, 10)
+}
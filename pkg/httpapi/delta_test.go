@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+func TestGetDelta(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+	ts := newTestServer(t, newFakeClient(t), WithChangeLog(cl))
+
+	req := makeClientRequest(t, ts, "/backstage/delta")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, map[string]interface{}{
+		"cursor": "2",
+		"entities": []any{
+			componentMap("mysql", "team-a"),
+			componentMap("nginx", "team-a"),
+		},
+	})
+
+	cl.Update([]backstage.Component{newComponent("mysql", "team-b"), newComponent("redis", "team-a")})
+
+	req = makeClientRequest(t, ts, "/backstage/delta?cursor=2")
+	res, err = ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertYAMLResponse(t, res, map[string]interface{}{
+		"cursor":  "5",
+		"added":   []any{componentMap("redis", "team-a")},
+		"updated": []any{componentMap("mysql", "team-b")},
+		"removed": []any{"nginx"},
+	})
+}
+
+func TestGetDeltaWithExpiredCursor(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	ts := newTestServer(t, newFakeClient(t), WithChangeLog(cl))
+
+	req := makeClientRequest(t, ts, "/backstage/delta?cursor=10")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusGone {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusGone)
+	}
+}
+
+func TestGetDeltaWithoutChangeLog(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t))
+
+	req := makeClientRequest(t, ts, "/backstage/delta")
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusNotFound)
+	}
+}
+
+func newComponent(name, owner string) backstage.Component {
+	return backstage.Component{
+		APIVersion: backstage.APIVersion,
+		Kind:       backstage.KindComponent,
+		Metadata:   backstage.BackstageMetadata{Name: name},
+		Spec:       backstage.ComponentSpec{Owner: owner},
+	}
+}
+
+func componentMap(name, owner string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "backstage.io/v1alpha1",
+		"kind":       "Component",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"lifecycle": "",
+			"owner":     owner,
+			"type":      "",
+			"system":    "",
+		},
+	}
+}
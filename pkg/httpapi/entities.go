@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// entitiesResponse is the response to a query for entities, this mirrors
+// the response from the Backstage catalog's /entities/by-query endpoint.
+type entitiesResponse struct {
+	Items      []map[string]any `yaml:"items"`
+	TotalItems int              `yaml:"totalItems"`
+	PageInfo   pageInfo         `yaml:"pageInfo"`
+}
+
+type pageInfo struct {
+	NextCursor string `yaml:"nextCursor,omitempty"`
+}
+
+// entitiesQuery is the parsed set of query parameters for the entities
+// endpoint.
+type entitiesQuery struct {
+	filters []entityFilter
+	fields  []string
+	limit   int
+	offset  int
+}
+
+func parseEntitiesQuery(r *http.Request) (entitiesQuery, error) {
+	q := entitiesQuery{}
+	values := r.URL.Query()
+	for _, raw := range values["filter"] {
+		f, err := parseEntityFilter(raw)
+		if err != nil {
+			return q, err
+		}
+		q.filters = append(q.filters, f)
+	}
+
+	for _, raw := range values["fields"] {
+		for _, f := range strings.Split(raw, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				q.fields = append(q.fields, f)
+			}
+		}
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return q, fmt.Errorf("invalid limit %q", raw)
+		}
+		q.limit = limit
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		offset, err := decodeCursor(raw)
+		if err != nil {
+			return q, err
+		}
+		q.offset = offset
+	}
+
+	return q, nil
+}
+
+func (a *BackstageRouter) handleEntities(w http.ResponseWriter, r *http.Request) {
+	a.loggerFrom(r.Context()).V(1).Info("querying entities", "path", r.URL.String())
+	query, err := parseEntitiesQuery(r)
+	if err != nil {
+		writeError(w, r, newInputError(err.Error(), nil))
+		return
+	}
+	scope, err := scopeFromRequest(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	components, err := a.components(r.Context(), scope)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	matched := []map[string]any{}
+	for _, v := range components {
+		entity, err := entityToMap(v)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if matchesAny(query.filters, entity) {
+			matched = append(matched, entity)
+		}
+	}
+
+	response := entitiesResponse{TotalItems: len(matched), Items: []map[string]any{}}
+	page := matched[min(query.offset, len(matched)):]
+	if query.limit > 0 && len(page) > query.limit {
+		page = page[:query.limit]
+		response.PageInfo.NextCursor = encodeCursor(query.offset + query.limit)
+	}
+	for _, v := range page {
+		if len(query.fields) > 0 {
+			v = projectFields(v, query.fields)
+		}
+		response.Items = append(response.Items, v)
+	}
+
+	marshalResponse(w, r, response)
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(s string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", s)
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", s)
+	}
+	return offset, nil
+}
@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestGetEntities(t *testing.T) {
+	mysql := test.NewDeployment("mysql", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel:      "mysql",
+			createdByLabel: "team-a",
+			componentLabel: "database",
+		}),
+		test.WithAnnotations(map[string]string{
+			backstage.LifecycleAnnotation:  "production",
+			"backstage.io/kubernetes-tags": "java,data",
+		}),
+	)
+	nginx := test.NewDeployment("nginx", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel:      "nginx",
+			createdByLabel: "team-a",
+			componentLabel: "webserver",
+		}),
+		test.WithAnnotations(map[string]string{
+			backstage.LifecycleAnnotation: "staging",
+		}),
+	)
+	redis := test.NewDeployment("redis", "test-ns",
+		test.WithLabels(map[string]string{
+			nameLabel:      "redis",
+			createdByLabel: "team-b",
+			componentLabel: "database",
+		}),
+	)
+	ts := newTestServer(t, newFakeClient(t, &mysql, &nginx, &redis))
+
+	entityTests := []struct {
+		name string
+		path string
+		want map[string]interface{}
+	}{
+		{
+			name: "filter with multiple conditions",
+			path: "/backstage/entities?filter=spec.owner=team-a,spec.lifecycle=production&fields=metadata.name,spec.owner",
+			want: entitiesPage(map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "mysql"},
+				"spec":     map[string]interface{}{"owner": "team-a"},
+			}),
+		},
+		{
+			name: "filter on list values",
+			path: "/backstage/entities?filter=metadata.tags=JAVA&fields=metadata.name",
+			want: entitiesPage(map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "mysql"},
+			}),
+		},
+		{
+			name: "multiple filters are alternatives",
+			path: "/backstage/entities?filter=spec.owner=team-b&filter=spec.lifecycle=staging&fields=metadata.name",
+			want: entitiesPage(
+				map[string]interface{}{"metadata": map[string]interface{}{"name": "nginx"}},
+				map[string]interface{}{"metadata": map[string]interface{}{"name": "redis"}},
+			),
+		},
+		{
+			name: "first page",
+			path: "/backstage/entities?filter=spec.type=database&fields=metadata.name&limit=1",
+			want: entitiesTotal(2, encodeCursor(1), map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "mysql"},
+			}),
+		},
+		{
+			name: "second page",
+			path: "/backstage/entities?filter=spec.type=database&fields=metadata.name&limit=1&cursor=" + encodeCursor(1),
+			want: entitiesTotal(2, "", map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "redis"},
+			}),
+		},
+	}
+
+	for _, tt := range entityTests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := makeClientRequest(t, ts, tt.path)
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertYAMLResponse(t, res, tt.want)
+		})
+	}
+}
+
+func TestGetEntitiesWithInvalidQuery(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t))
+
+	for _, path := range []string{
+		"/backstage/entities?limit=0",
+		"/backstage/entities?cursor=not-a-cursor",
+		"/backstage/entities?filter==value",
+	} {
+		t.Run(path, func(t *testing.T) {
+			req := makeClientRequest(t, ts, path)
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func entitiesPage(items ...map[string]interface{}) map[string]interface{} {
+	return entitiesTotal(len(items), "", items...)
+}
+
+func entitiesTotal(total int, cursor string, items ...map[string]interface{}) map[string]interface{} {
+	result := []any{}
+	for _, v := range items {
+		result = append(result, v)
+	}
+	info := map[string]interface{}{}
+	if cursor != "" {
+		info["nextCursor"] = cursor
+	}
+	return map[string]interface{}{
+		"items":      result,
+		"totalItems": total,
+		"pageInfo":   info,
+	}
+}
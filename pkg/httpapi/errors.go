@@ -0,0 +1,154 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Error names, these match the names of the errors in the Backstage
+// @backstage/errors package.
+const (
+	inputError              = "InputError"
+	authenticationError     = "AuthenticationError"
+	notAllowedError         = "NotAllowedError"
+	notFoundError           = "NotFoundError"
+	goneError               = "GoneError"
+	serviceUnavailableError = "ServiceUnavailableError"
+	internalError           = "Error"
+)
+
+// apiError is an error that is returned to the client with a status code.
+type apiError struct {
+	status  int
+	name    string
+	message string
+	cause   error
+}
+
+func (e *apiError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", e.message, e.cause)
+	}
+	return e.message
+}
+
+func (e *apiError) Unwrap() error {
+	return e.cause
+}
+
+func newInputError(message string, cause error) *apiError {
+	return &apiError{status: http.StatusBadRequest, name: inputError, message: message, cause: cause}
+}
+
+func newNotFoundError(message string) *apiError {
+	return &apiError{status: http.StatusNotFound, name: notFoundError, message: message}
+}
+
+// kubernetesError wraps an error from the Kubernetes API, mapping the
+// status to an appropriate response e.g. a missing RBAC permission is
+// returned as a 403.
+func kubernetesError(message string, err error) *apiError {
+	e := &apiError{status: http.StatusInternalServerError, name: internalError, message: message, cause: err}
+	switch {
+	case apierrors.IsForbidden(err):
+		e.status, e.name = http.StatusForbidden, notAllowedError
+	case apierrors.IsNotFound(err):
+		e.status, e.name = http.StatusNotFound, notFoundError
+	case apierrors.IsServiceUnavailable(err), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err):
+		e.status, e.name = http.StatusServiceUnavailable, serviceUnavailableError
+	}
+	return e
+}
+
+// errorResponse is the body of error responses, this follows the Backstage
+// ErrorResponseBody.
+type errorResponse struct {
+	Error    errorBody    `json:"error" yaml:"error"`
+	Request  errorRequest `json:"request" yaml:"request"`
+	Response errorStatus  `json:"response" yaml:"response"`
+}
+
+type errorBody struct {
+	Name    string     `json:"name" yaml:"name"`
+	Message string     `json:"message" yaml:"message"`
+	Cause   *errorBody `json:"cause,omitempty" yaml:"cause,omitempty"`
+}
+
+type errorRequest struct {
+	Method    string `json:"method" yaml:"method"`
+	URL       string `json:"url" yaml:"url"`
+	RequestID string `json:"requestId,omitempty" yaml:"requestId,omitempty"`
+}
+
+type errorStatus struct {
+	StatusCode int `json:"statusCode" yaml:"statusCode"`
+}
+
+// writeError writes the error to the response, as JSON if the client
+// accepts it, otherwise as YAML.
+//
+// Errors that are not an apiError are returned as internal server errors.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *apiError
+	if !errors.As(err, &e) {
+		e = &apiError{status: http.StatusInternalServerError, name: internalError, message: err.Error()}
+	}
+	logger := logr.FromContextOrDiscard(r.Context())
+	if e.status >= http.StatusInternalServerError {
+		logger.Error(err, "request failed", "status", e.status)
+	}
+
+	body := errorResponse{
+		Error: errorBody{Name: e.name, Message: e.message, Cause: errorCause(e.cause)},
+		Request: errorRequest{
+			Method:    r.Method,
+			URL:       r.URL.RequestURI(),
+			RequestID: requestIDFrom(r.Context()),
+		},
+		Response: errorStatus{StatusCode: e.status},
+	}
+
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(e.status)
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			logger.Error(err, "failed to encode error response")
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(e.status)
+	if err := yaml.NewEncoder(w).Encode(body); err != nil {
+		logger.Error(err, "failed to encode error response")
+	}
+}
+
+// errorCause describes the cause of an error, including the reason from
+// Kubernetes API errors.
+func errorCause(err error) *errorBody {
+	if err == nil {
+		return nil
+	}
+	name := "Error"
+	if reason := apierrors.ReasonForError(err); reason != "" {
+		name = string(reason)
+	}
+	return &errorBody{Name: name, Message: err.Error()}
+}
+
+func acceptsJSON(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(v), ";")
+		if mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
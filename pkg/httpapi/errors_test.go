@@ -0,0 +1,200 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestErrorResponses(t *testing.T) {
+	errorTests := []struct {
+		name   string
+		client client.Client
+		method string
+		path   string
+		want   errorResponse
+	}{
+		{
+			name:   "invalid selector",
+			client: newFakeClient(t),
+			path:   "/backstage/catalog-info.yaml?labelSelector=a%3D%3D%3D",
+			want: errorResponse{
+				Error: errorBody{
+					Name:    inputError,
+					Message: `invalid label selector "a==="`,
+					Cause: &errorBody{
+						Name:    "Error",
+						Message: "unable to parse requirement: found '=', expected: identifier",
+					},
+				},
+				Request:  errorRequest{Method: http.MethodGet, URL: "/backstage/catalog-info.yaml?labelSelector=a%3D%3D%3D", RequestID: "test-request"},
+				Response: errorStatus{StatusCode: http.StatusBadRequest},
+			},
+		},
+		{
+			name:   "unknown component",
+			client: newFakeClient(t),
+			path:   "/backstage/component/unknown/info.yaml",
+			want: errorResponse{
+				Error: errorBody{
+					Name:    notFoundError,
+					Message: `component "unknown" not found`,
+				},
+				Request:  errorRequest{Method: http.MethodGet, URL: "/backstage/component/unknown/info.yaml", RequestID: "test-request"},
+				Response: errorStatus{StatusCode: http.StatusNotFound},
+			},
+		},
+		{
+			name:   "unknown path",
+			client: newFakeClient(t),
+			path:   "/backstage/unknown",
+			want: errorResponse{
+				Error: errorBody{
+					Name:    notFoundError,
+					Message: `path "/backstage/unknown" not found`,
+				},
+				Request:  errorRequest{Method: http.MethodGet, URL: "/backstage/unknown", RequestID: "test-request"},
+				Response: errorStatus{StatusCode: http.StatusNotFound},
+			},
+		},
+		{
+			name:   "method not allowed",
+			client: newFakeClient(t),
+			method: http.MethodPost,
+			path:   "/backstage/entities",
+			want: errorResponse{
+				Error: errorBody{
+					Name:    inputError,
+					Message: `method POST is not allowed for path "/backstage/entities"`,
+				},
+				Request:  errorRequest{Method: http.MethodPost, URL: "/backstage/entities", RequestID: "test-request"},
+				Response: errorStatus{StatusCode: http.StatusMethodNotAllowed},
+			},
+		},
+		{
+			name: "forbidden list",
+			client: newErrorClient(t, apierrors.NewForbidden(
+				schema.GroupResource{Group: "apps", Resource: "deployments"}, "", nil)),
+			path: "/backstage/catalog-info.yaml",
+			want: errorResponse{
+				Error: errorBody{
+					Name:    notAllowedError,
+					Message: "failed to load components",
+					Cause: &errorBody{
+						Name:    "Forbidden",
+						Message: `failed to load deployments: deployments.apps is forbidden: <nil>`,
+					},
+				},
+				Request:  errorRequest{Method: http.MethodGet, URL: "/backstage/catalog-info.yaml", RequestID: "test-request"},
+				Response: errorStatus{StatusCode: http.StatusForbidden},
+			},
+		},
+		{
+			name:   "unavailable list",
+			client: newErrorClient(t, apierrors.NewServiceUnavailable("etcd is unavailable")),
+			path:   "/backstage/entities",
+			want: errorResponse{
+				Error: errorBody{
+					Name:    serviceUnavailableError,
+					Message: "failed to load components",
+					Cause: &errorBody{
+						Name:    "ServiceUnavailable",
+						Message: "failed to load deployments: etcd is unavailable",
+					},
+				},
+				Request:  errorRequest{Method: http.MethodGet, URL: "/backstage/entities", RequestID: "test-request"},
+				Response: errorStatus{StatusCode: http.StatusServiceUnavailable},
+			},
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.client)
+			req := makeClientRequest(t, ts, tt.path, func(r *http.Request) {
+				r.Header.Set(requestIDHeader, "test-request")
+				if tt.method != "" {
+					r.Method = tt.method
+				}
+			})
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.want.Response.StatusCode {
+				t.Errorf("got status %v, want %v", res.StatusCode, tt.want.Response.StatusCode)
+			}
+			if h := res.Header.Get("Content-Type"); h != "application/yaml" {
+				t.Errorf("got Content-Type %q, want application/yaml", h)
+			}
+			var got errorResponse
+			if err := yaml.NewDecoder(res.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("failed to get error response:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestErrorResponseJSON(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t))
+	req := makeClientRequest(t, ts, "/backstage/component/unknown/info.yaml", func(r *http.Request) {
+		r.Header.Set("Accept", "application/yaml;q=0.9, application/json")
+	})
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if h := res.Header.Get("Content-Type"); h != "application/json" {
+		t.Errorf("got Content-Type %q, want application/json", h)
+	}
+	var got errorResponse
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Error.Name != notFoundError {
+		t.Errorf("got error name %q, want %q", got.Error.Name, notFoundError)
+	}
+	if got.Request.RequestID == "" {
+		t.Error("no request ID in the error response")
+	}
+	if got.Response.StatusCode != http.StatusNotFound {
+		t.Errorf("got status code %v, want %v", got.Response.StatusCode, http.StatusNotFound)
+	}
+}
+
+// newErrorClient returns a client that fails to list resources with the
+// provided error.
+func newErrorClient(t *testing.T, listErr error) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				return listErr
+			},
+		}).
+		Build()
+}
@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+const (
+	eventUpsert = "upsert"
+	eventDelete = "delete"
+	// eventReset is sent when a client resumes from an event that is no
+	// longer available, the client should fetch a new snapshot.
+	eventReset = "reset"
+)
+
+// keepAliveInterval is how often a comment is sent to idle event streams to
+// stop intermediaries from closing the connection.
+var keepAliveInterval = 30 * time.Second
+
+type deleteEvent struct {
+	Name string `json:"name"`
+}
+
+// handleEvents streams changes to the catalog as Server-Sent Events.
+//
+// Each event has the revision of the change as its ID, clients that
+// reconnect with a Last-Event-ID header receive the changes that they
+// missed.
+func (a *BackstageRouter) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.New("streaming is not supported"))
+		return
+	}
+
+	notify, cancel := a.changes.Subscribe()
+	defer cancel()
+
+	_, revision := a.changes.Snapshot()
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeError(w, r, newInputError(fmt.Sprintf("invalid Last-Event-ID %q", raw), err))
+			return
+		}
+		revision = parsed
+	}
+	a.loggerFrom(r.Context()).V(1).Info("streaming events", "revision", revision)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		changes, current, err := a.changes.Changes(revision)
+		if errors.Is(err, catalog.ErrRevisionExpired) {
+			writeEvent(w, current, eventReset, struct{}{})
+			changes = nil
+		}
+		for _, v := range changes {
+			if v.Type == catalog.ChangeRemoved {
+				writeEvent(w, v.Revision, eventDelete, deleteEvent{Name: v.Name})
+				continue
+			}
+			writeEvent(w, v.Revision, eventUpsert, v.Entity)
+		}
+		revision = current
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.shutdown:
+			return
+		case <-notify:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, id uint64, event string, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		// This should never happen with the types that are sent.
+		b = []byte("{}")
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, b)
+}
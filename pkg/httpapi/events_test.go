@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+)
+
+func TestGetEvents(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+	ts := newTestServer(t, newFakeClient(t), WithChangeLog(cl))
+
+	events := streamEvents(t, ts.Client(), makeClientRequest(t, ts, "/backstage/events"))
+
+	cl.Update([]backstage.Component{newComponent("mysql", "team-b")})
+
+	want := []sseEvent{
+		{id: "3", event: "upsert", data: `{"apiVersion":"backstage.io/v1alpha1","kind":"Component","metadata":{"name":"mysql"},"spec":{"type":"","lifecycle":"","owner":"team-b","system":""}}`},
+		{id: "4", event: "delete", data: `{"name":"nginx"}`},
+	}
+	if diff := cmp.Diff(want, readEvents(t, events, 2), cmp.AllowUnexported(sseEvent{})); diff != "" {
+		t.Fatalf("failed to stream events:\n%s", diff)
+	}
+}
+
+func TestGetEventsWithLastEventID(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a"), newComponent("nginx", "team-a")})
+	ts := newTestServer(t, newFakeClient(t), WithChangeLog(cl))
+
+	events := streamEvents(t, ts.Client(), makeClientRequest(t, ts, "/backstage/events", func(r *http.Request) {
+		r.Header.Set("Last-Event-ID", "1")
+	}))
+
+	want := []sseEvent{
+		{id: "2", event: "upsert", data: `{"apiVersion":"backstage.io/v1alpha1","kind":"Component","metadata":{"name":"nginx"},"spec":{"type":"","lifecycle":"","owner":"team-a","system":""}}`},
+	}
+	if diff := cmp.Diff(want, readEvents(t, events, 1), cmp.AllowUnexported(sseEvent{})); diff != "" {
+		t.Fatalf("failed to stream events:\n%s", diff)
+	}
+}
+
+func TestGetEventsWithExpiredLastEventID(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	cl.Update([]backstage.Component{newComponent("mysql", "team-a")})
+	ts := newTestServer(t, newFakeClient(t), WithChangeLog(cl))
+
+	events := streamEvents(t, ts.Client(), makeClientRequest(t, ts, "/backstage/events", func(r *http.Request) {
+		r.Header.Set("Last-Event-ID", "10")
+	}))
+
+	want := []sseEvent{
+		{id: "1", event: "reset", data: `{}`},
+	}
+	if diff := cmp.Diff(want, readEvents(t, events, 1), cmp.AllowUnexported(sseEvent{})); diff != "" {
+		t.Fatalf("failed to stream events:\n%s", diff)
+	}
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// streamEvents makes the request and parses the response body as
+// Server-Sent Events.
+func streamEvents(t *testing.T, c *http.Client, req *http.Request) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	res, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := res.Header.Get("Content-Type"); h != "text/event-stream" {
+		t.Fatalf("wanted 'text/event-stream' got %s", h)
+	}
+
+	events := make(chan sseEvent)
+	go func() {
+		defer res.Body.Close()
+		scanner := bufio.NewScanner(res.Body)
+		current := sseEvent{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if current.event != "" {
+					select {
+					case events <- current:
+					case <-ctx.Done():
+						return
+					}
+				}
+				current = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				current.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+func readEvents(t *testing.T, events <-chan sseEvent, n int) []sseEvent {
+	t.Helper()
+	result := []sseEvent{}
+	for len(result) < n {
+		select {
+		case e := <-events:
+			result = append(result, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, got %v", result)
+		}
+	}
+	return result
+}
+
+func TestGetEventsEndsOnShutdown(t *testing.T) {
+	cl := catalog.NewChangeLog(10)
+	router := NewRouter(zapr.NewLogger(zap.NewNop()), newFakeClient(t), WithChangeLog(cl))
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	res, err := ts.Client().Get(ts.URL + "/backstage/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	router.Shutdown()
+
+	done := make(chan error)
+	go func() {
+		_, err := io.ReadAll(res.Body)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream did not end on shutdown")
+	}
+}
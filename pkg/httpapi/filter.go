@@ -0,0 +1,161 @@
+package httpapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// entityFilter is a set of conditions that must all match an entity.
+//
+// This follows the Backstage catalog API, a filter parameter like
+// "kind=component,spec.owner=team-a" matches entities where both conditions
+// match, a key without a value e.g. "spec.system" matches entities where the
+// field exists.
+type entityFilter []filterCondition
+
+type filterCondition struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+func parseEntityFilter(raw string) (entityFilter, error) {
+	f := entityFilter{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid filter %q: missing key", part)
+		}
+		f = append(f, filterCondition{key: key, value: strings.TrimSpace(value), hasValue: hasValue})
+	}
+	return f, nil
+}
+
+// matches returns true if all the conditions in the filter match the entity.
+//
+// Values are compared case-insensitively, and a condition matches a list
+// field if any of the elements in the list matches. Empty values are treated
+// as absent, as fields like spec.system are serialised even when they're not
+// set.
+func (f entityFilter) matches(entity map[string]any) bool {
+	for _, c := range f {
+		values := slices.DeleteFunc(lookupField(entity, c.key), isEmptyValue)
+		if len(values) == 0 {
+			return false
+		}
+		if !c.hasValue {
+			continue
+		}
+		found := false
+		for _, v := range values {
+			if s, ok := v.(string); ok && strings.EqualFold(s, c.value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptyValue(v any) bool {
+	return v == nil || v == ""
+}
+
+// matchesAny returns true if any of the filters match the entity, or if there
+// are no filters.
+func matchesAny(filters []entityFilter, entity map[string]any) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if f.matches(entity) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupField finds the values at the dotted path in the entity.
+//
+// Keys in the entity can contain dots e.g. annotations like
+// "backstage.io/kubernetes-id", so the path is matched against the keys in
+// each map rather than being split up front.
+//
+// Lists are flattened into the returned values.
+func lookupField(v any, path string) []any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	result := []any{}
+	for k, child := range m {
+		switch {
+		case strings.EqualFold(k, path):
+			if l, ok := child.([]any); ok {
+				result = append(result, l...)
+				continue
+			}
+			result = append(result, child)
+		case len(path) > len(k) && strings.EqualFold(path[:len(k)+1], k+"."):
+			result = append(result, lookupField(child, path[len(k)+1:])...)
+		}
+	}
+	return result
+}
+
+// projectFields returns a copy of the entity with only the dotted paths in
+// fields.
+func projectFields(entity map[string]any, fields []string) map[string]any {
+	result := map[string]any{}
+	for _, f := range fields {
+		projectField(entity, result, f)
+	}
+	return result
+}
+
+func projectField(src, dst map[string]any, path string) {
+	for k, child := range src {
+		switch {
+		case strings.EqualFold(k, path):
+			dst[k] = child
+		case len(path) > len(k) && strings.EqualFold(path[:len(k)+1], k+"."):
+			childMap, ok := child.(map[string]any)
+			if !ok {
+				continue
+			}
+			next, ok := dst[k].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+			}
+			projectField(childMap, next, path[len(k)+1:])
+			if len(next) > 0 {
+				dst[k] = next
+			}
+		}
+	}
+}
+
+// entityToMap converts an entity to a generic map via its YAML
+// representation, so that the field names match the ones in the output.
+func entityToMap(v any) (map[string]any, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entity: %w", err)
+	}
+	result := map[string]any{}
+	if err := yaml.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entity: %w", err)
+	}
+	return result, nil
+}
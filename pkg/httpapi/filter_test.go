@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLookupField(t *testing.T) {
+	entity := map[string]any{
+		"metadata": map[string]any{
+			"name": "mysql",
+			"tags": []any{"java", "data"},
+			"annotations": map[string]any{
+				"backstage.io/kubernetes-id": "testing",
+			},
+		},
+	}
+
+	lookupTests := []struct {
+		path string
+		want []any
+	}{
+		{"metadata.name", []any{"mysql"}},
+		{"Metadata.Name", []any{"mysql"}},
+		{"metadata.tags", []any{"java", "data"}},
+		{"metadata.annotations.backstage.io/kubernetes-id", []any{"testing"}},
+		{"metadata.unknown", []any{}},
+		{"metadata.name.unknown", []any{}},
+	}
+
+	for _, tt := range lookupTests {
+		t.Run(tt.path, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, lookupField(entity, tt.path)); diff != "" {
+				t.Fatalf("failed lookup:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEntityFilter(t *testing.T) {
+	entity := map[string]any{
+		"kind": "Component",
+		"metadata": map[string]any{
+			"name": "mysql",
+			"tags": []any{"java", "data"},
+		},
+		"spec": map[string]any{
+			"owner":  "team-a",
+			"system": "",
+		},
+	}
+
+	filterTests := []struct {
+		filter string
+		want   bool
+	}{
+		{"kind=component", true},
+		{"kind=component,spec.owner=team-a", true},
+		{"kind=component,spec.owner=team-b", false},
+		{"metadata.tags=data", true},
+		{"metadata.tags", true},
+		{"spec.system", false},
+		{"spec.system=", false},
+		{"spec.unknown", false},
+	}
+
+	for _, tt := range filterTests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseEntityFilter(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.matches(entity); got != tt.want {
+				t.Fatalf("matches() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	entity := map[string]any{
+		"kind": "Component",
+		"metadata": map[string]any{
+			"name": "mysql",
+			"annotations": map[string]any{
+				"backstage.io/kubernetes-id": "testing",
+				"backstage.io/other":         "other",
+			},
+		},
+	}
+
+	got := projectFields(entity, []string{"kind", "metadata.annotations.backstage.io/kubernetes-id", "spec.owner"})
+	want := map[string]any{
+		"kind": "Component",
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				"backstage.io/kubernetes-id": "testing",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("failed projection:\n%s", diff)
+	}
+}
@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// readinessTimeout is the maximum time that the readiness checks can take.
+const readinessTimeout = 5 * time.Second
+
+// ReadinessCheck is a named check that must pass before the server is
+// ready to serve requests.
+type ReadinessCheck struct {
+	Name  string
+	Check func(context.Context) error
+}
+
+// AdminHandler returns a handler that serves the health and metrics
+// endpoints without authentication, for use on a separate listener.
+func (a *BackstageRouter) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.HandleFunc("GET /readyz", a.handleReadyz)
+	if a.metrics != nil {
+		mux.Handle("GET /metrics", a.metrics.Handler())
+	}
+	return mux
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+func (a *BackstageRouter) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	failures := []string{}
+	for _, v := range a.readyChecks {
+		if err := v.Check(ctx); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", v.Name, err))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	if len(failures) > 0 {
+		a.loggerFrom(r.Context()).Info("readiness check failed", "failures", failures)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(failures, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
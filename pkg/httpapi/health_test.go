@@ -0,0 +1,141 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/metrics"
+)
+
+func TestHealthz(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t))
+
+	res := getPath(t, ts, "/healthz")
+	assertTextResponse(t, res, http.StatusOK, "ok\n")
+}
+
+func TestReadyz(t *testing.T) {
+	readyErr := errors.New("not ready")
+	check := func(context.Context) error { return readyErr }
+	ts := newTestServer(t, newFakeClient(t), WithReadinessCheck("test", check))
+
+	res := getPath(t, ts, "/readyz")
+	assertTextResponse(t, res, http.StatusServiceUnavailable, "test: not ready\n")
+
+	readyErr = nil
+	res = getPath(t, ts, "/readyz")
+	assertTextResponse(t, res, http.StatusOK, "ok\n")
+}
+
+func TestMetrics(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t), WithMetrics(metrics.New()))
+
+	res := getPath(t, ts, "/backstage/catalog-info.yaml")
+	res.Body.Close()
+
+	res = getPath(t, ts, "/metrics")
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`peanut_backstage_http_requests_total{code="200",method="GET",route="/backstage/catalog-info.yaml"} 1`,
+		`peanut_backstage_parse_duration_seconds_count 1`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("metrics did not contain %q", want)
+		}
+	}
+}
+
+func TestUnavailableClustersDontChangeAvailability(t *testing.T) {
+	failing := interceptor.NewClient(newFakeClient(t).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return apierrors.NewServiceUnavailable("cluster is unavailable")
+		},
+	})
+	m := metrics.New()
+	m.SetClusterAvailable("dev", true)
+	ts := newTestServer(t, newFakeClient(t), WithMetrics(m), WithClusters(catalog.MergeDuplicates,
+		catalog.Cluster{Name: "dev", Client: failing},
+		catalog.Cluster{Name: "staging", Client: newFakeClient(t)},
+	))
+
+	res := getPath(t, ts, "/backstage/catalog-info.yaml")
+	res.Body.Close()
+	if h := res.Header.Get("X-Catalog-Unavailable-Clusters"); h != "dev" {
+		t.Errorf("got unavailable clusters %q, want %q", h, "dev")
+	}
+
+	res = getPath(t, ts, "/metrics")
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`peanut_backstage_cluster_available{cluster="dev"} 1`,
+		`peanut_backstage_diagnostics_total{reason="ClusterUnavailable"} 1`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("metrics did not contain %q", want)
+		}
+	}
+	if strings.Contains(string(b), `cluster="staging"`) {
+		t.Error("request set the availability of staging")
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	router := NewRouter(zapr.NewLogger(zap.NewNop()), newFakeClient(t), WithMetrics(metrics.New()))
+	ts := httptest.NewServer(router.AdminHandler())
+	t.Cleanup(ts.Close)
+
+	for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+		res, err := ts.Client().Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s: got status %v, want %v", path, res.StatusCode, http.StatusOK)
+		}
+	}
+}
+
+func getPath(t *testing.T, ts *httptest.Server, path string) *http.Response {
+	t.Helper()
+	res, err := ts.Client().Do(makeClientRequest(t, ts, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func assertTextResponse(t *testing.T, res *http.Response, status int, want string) {
+	t.Helper()
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != status {
+		t.Errorf("got status %v, want %v", res.StatusCode, status)
+	}
+	if string(b) != want {
+		t.Errorf("got body %q, want %q", b, want)
+	}
+}
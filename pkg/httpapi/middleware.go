@@ -0,0 +1,177 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/auth"
+)
+
+// unauthenticatedPaths are not subject to authentication, so that they can
+// be used by Kubernetes probes.
+var unauthenticatedPaths = []string{
+	"/healthz",
+	"/readyz",
+}
+
+// authenticate wraps the handler, rejecting requests that are not
+// authenticated by the router's authenticator.
+//
+// The authenticated User is added to the request context.
+func (a *BackstageRouter) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(unauthenticatedPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok, err := a.authenticator.Authenticate(r)
+		if err != nil {
+			a.loggerFrom(r.Context()).Error(err, "failed to authenticate request", "path", r.URL.Path)
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="peanut-backstage"`)
+			writeError(w, r, &apiError{
+				status:  http.StatusUnauthorized,
+				name:    authenticationError,
+				message: "the request is not authenticated",
+			})
+			return
+		}
+
+		if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+			info.user = user.Name
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	})
+}
+
+// statusRecorder records the status code and number of bytes written to
+// the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush implements the http.Flusher interface, so that streaming responses
+// can be recorded.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// requestIDHeader is the header used to correlate requests, an incoming ID
+// is used if it's valid, otherwise one is generated.
+const requestIDHeader = "X-Request-ID"
+
+type requestInfoKey struct{}
+
+// requestInfo is shared between the middleware for a request, so that the
+// access log can include details that are only known further down the
+// chain.
+type requestInfo struct {
+	id   string
+	user string
+	// stale is true if the response was built from a snapshot that was
+	// taken age ago.
+	stale bool
+	age   time.Duration
+	// unavailable are the clusters that were left out of the response.
+	unavailable []string
+}
+
+// logRequests wraps the handler, assigning an ID to each request, adding a
+// logger with the ID to the request context and writing an access log
+// entry when the request completes.
+func (a *BackstageRouter) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{id: r.Header.Get(requestIDHeader)}
+		if !validRequestID(info.id) {
+			info.id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, info.id)
+
+		logger := a.logger.WithValues("requestID", info.id)
+		ctx := context.WithValue(logr.NewContext(r.Context(), logger), requestInfoKey{}, info)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"user", info.user,
+			"remoteAddr", r.RemoteAddr,
+			"stale", info.stale,
+		)
+	})
+}
+
+// loggerFrom returns the logger for the request from the context, or the
+// router's logger if there isn't one.
+func (a *BackstageRouter) loggerFrom(ctx context.Context) logr.Logger {
+	if l, err := logr.FromContext(ctx); err == nil {
+		return l
+	}
+	return a.logger
+}
+
+// requestIDFrom returns the ID of the request from the context.
+func requestIDFrom(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// This is only used for correlation, so a less unique ID is
+		// acceptable.
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns true if the ID is safe to log and return in a
+// header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
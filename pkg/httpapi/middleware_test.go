@@ -0,0 +1,117 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/auth"
+)
+
+func TestAuthentication(t *testing.T) {
+	tokens, err := auth.ParseStaticTokens(strings.NewReader("test-token,alice,1001\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, newFakeClient(t), WithAuthenticator(tokens))
+
+	authTests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"no credentials", "/backstage/catalog-info.yaml", "", http.StatusUnauthorized},
+		{"invalid credentials", "/backstage/catalog-info.yaml", "Bearer unknown", http.StatusUnauthorized},
+		{"valid credentials", "/backstage/catalog-info.yaml", "Bearer test-token", http.StatusOK},
+		{"exempt path", "/healthz", "", http.StatusOK},
+	}
+
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := makeClientRequest(t, ts, tt.path, func(r *http.Request) {
+				if tt.authorization != "" {
+					r.Header.Set("Authorization", tt.authorization)
+				}
+			})
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("got status %v, want %v", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && res.Header.Get("WWW-Authenticate") == "" {
+				t.Fatal("no WWW-Authenticate header in unauthorized response")
+			}
+		})
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	logs := make(chan string, 10)
+	logger := funcr.New(func(prefix, args string) {
+		logs <- args
+	}, funcr.Options{})
+	tokens, err := auth.ParseStaticTokens(strings.NewReader("test-token,alice,1001\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter(logger, newFakeClient(t), WithAuthenticator(tokens))
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	req := makeClientRequest(t, ts, "/backstage/catalog-info.yaml", func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer test-token")
+		r.Header.Set("X-Request-ID", "test-request-id")
+	})
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if id := res.Header.Get("X-Request-ID"); id != "test-request-id" {
+		t.Errorf("got request ID %q, want %q", id, "test-request-id")
+	}
+
+	// The access log is written after the response is sent.
+	var accessLog string
+	select {
+	case accessLog = <-logs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the access log")
+	}
+	for _, want := range []string{
+		`"msg"="request" "requestID"="test-request-id" "method"="GET" "path"="/backstage/catalog-info.yaml" "status"=200`,
+		`"user"="alice"`,
+	} {
+		if !strings.Contains(accessLog, want) {
+			t.Errorf("access log %q did not contain %q", accessLog, want)
+		}
+	}
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	ts := newTestServer(t, newFakeClient(t))
+
+	for _, id := range []string{"", "invalid id with spaces"} {
+		req := makeClientRequest(t, ts, "/healthz", func(r *http.Request) {
+			if id != "" {
+				r.Header.Set("X-Request-ID", id)
+			}
+		})
+		res, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got := res.Header.Get("X-Request-ID"); got == "" || got == id {
+			t.Errorf("got request ID %q, want a generated ID", got)
+		}
+	}
+}
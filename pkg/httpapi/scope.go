@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/julienschmidt/httprouter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+)
+
+const (
+	labelSelectorParam = "labelSelector"
+	fieldSelectorParam = "fieldSelector"
+)
+
+// scope restricts the components that are returned from a request.
+//
+// Namespaces and selectors are filtered when listing resources from the
+// cluster, owners and systems are filtered after the resources have been
+// parsed.
+type scope struct {
+	namespace     string
+	owner         string
+	system        string
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+}
+
+func scopeFromRequest(r *http.Request) (scope, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	s := scope{
+		namespace:     params.ByName("ns"),
+		owner:         params.ByName("owner"),
+		system:        params.ByName("system"),
+		labelSelector: labels.Everything(),
+		fieldSelector: fields.Everything(),
+	}
+
+	query := r.URL.Query()
+	if raw := query.Get(labelSelectorParam); raw != "" {
+		sel, err := labels.Parse(raw)
+		if err != nil {
+			return s, newInputError(fmt.Sprintf("invalid label selector %q", raw), err)
+		}
+		s.labelSelector = sel
+	}
+	if raw := query.Get(fieldSelectorParam); raw != "" {
+		sel, err := fields.ParseSelector(raw)
+		if err != nil {
+			return s, newInputError(fmt.Sprintf("invalid field selector %q", raw), err)
+		}
+		s.fieldSelector = sel
+	}
+
+	return s, nil
+}
+
+// withSelector adds the requirements from the provided selector to the
+// label selector for the scope.
+func (s scope) withSelector(sel labels.Selector) scope {
+	if reqs, selectable := sel.Requirements(); selectable && len(reqs) > 0 {
+		s.labelSelector = s.labelSelector.Add(reqs...)
+	}
+	return s
+}
+
+func (s scope) listOptions() []client.ListOption {
+	opts := []client.ListOption{}
+	if s.namespace != "" {
+		opts = append(opts, client.InNamespace(s.namespace))
+	}
+	if s.labelSelector != nil && !s.labelSelector.Empty() {
+		opts = append(opts, client.MatchingLabelsSelector{Selector: s.labelSelector})
+	}
+	if s.fieldSelector != nil && !s.fieldSelector.Empty() {
+		opts = append(opts, client.MatchingFieldsSelector{Selector: s.fieldSelector})
+	}
+	return opts
+}
+
+// filtered returns true if the scope is restricted by namespace or
+// selectors, which are applied when resources are listed.
+func (s scope) filtered() bool {
+	return s.namespace != "" ||
+		(s.labelSelector != nil && !s.labelSelector.Empty()) ||
+		(s.fieldSelector != nil && !s.fieldSelector.Empty())
+}
+
+// matchesObject returns true if a resource with the metadata is in the
+// namespace and matches the selectors for the scope, this filters resources
+// in the same way as listing them.
+func (s scope) matchesObject(m metav1.ObjectMeta) bool {
+	if s.namespace != "" && m.Namespace != s.namespace {
+		return false
+	}
+	if s.labelSelector != nil && !s.labelSelector.Matches(labels.Set(m.Labels)) {
+		return false
+	}
+	if s.fieldSelector != nil && !s.fieldSelector.Matches(fields.Set{"metadata.name": m.Name, "metadata.namespace": m.Namespace}) {
+		return false
+	}
+	return true
+}
+
+// selectorQuery returns the selector query parameters that must be carried
+// over to relative targets so that they are resolved with the same
+// selectors.
+func selectorQuery(r *http.Request) string {
+	values := url.Values{}
+	for _, k := range []string{labelSelectorParam, fieldSelectorParam} {
+		if v := r.URL.Query().Get(k); v != "" {
+			values.Set(k, v)
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+func (s scope) matches(c backstage.Component) bool {
+	if s.owner != "" && c.Spec.Owner != s.owner {
+		return false
+	}
+	if s.system != "" && c.Spec.System != s.system {
+		return false
+	}
+	return true
+}
+
+// location returns the name and description for the Location that is
+// generated for this scope, the root name and description are used when the
+// scope is unrestricted.
+func (s scope) location(rootName, rootDescription string) (string, string) {
+	switch {
+	case s.namespace != "":
+		return "namespace-" + s.namespace, fmt.Sprintf("Components in namespace %s", s.namespace)
+	case s.owner != "":
+		return "owner-" + s.owner, fmt.Sprintf("Components owned by %s", s.owner)
+	case s.system != "":
+		return "system-" + s.system, fmt.Sprintf("Components in system %s", s.system)
+	}
+	return rootName, rootDescription
+}
@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// staleHeader is set on responses that were built from a snapshot because
+// the resources could not be listed.
+const staleHeader = "X-Catalog-Stale"
+
+// unavailableHeader lists the clusters that were left out of the response
+// because they could not be listed.
+const unavailableHeader = "X-Catalog-Unavailable-Clusters"
+
+// snapshot is the last successful list of resources for a cluster.
+type snapshot struct {
+	items []appsv1.Deployment
+	taken time.Time
+}
+
+// snapshotCache keeps the last successful unfiltered list of resources for
+// each cluster so that the catalog can continue to be served while the
+// Kubernetes API is failing.
+//
+// Only the metadata of the resources is kept, and requests for namespaces
+// or with selectors are filtered from the snapshot, so the cache holds at
+// most one list per cluster. Snapshots older than maxAge are never served.
+type snapshotCache struct {
+	mu        sync.Mutex
+	maxAge    time.Duration
+	snapshots map[string]snapshot
+	now       func() time.Time
+}
+
+func newSnapshotCache(maxAge time.Duration) *snapshotCache {
+	return &snapshotCache{
+		maxAge:    maxAge,
+		snapshots: map[string]snapshot{},
+		now:       time.Now,
+	}
+}
+
+// put records the metadata of the items as the last good list for the
+// cluster.
+func (c *snapshotCache) put(cluster string, items []appsv1.Deployment) {
+	stripped := make([]appsv1.Deployment, len(items))
+	for i, v := range items {
+		stripped[i] = appsv1.Deployment{TypeMeta: v.TypeMeta, ObjectMeta: *v.ObjectMeta.DeepCopy()}
+		stripped[i].ManagedFields = nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots[cluster] = snapshot{items: stripped, taken: c.now()}
+}
+
+// get returns the items from the last good list for the cluster that are in
+// the scope, and the age of the list, if it hasn't expired.
+func (c *snapshotCache) get(cluster string, s scope) ([]appsv1.Deployment, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, ok := c.snapshots[cluster]
+	if !ok {
+		return nil, 0, false
+	}
+	age := c.now().Sub(snap.taken)
+	if age > c.maxAge {
+		delete(c.snapshots, cluster)
+		return nil, 0, false
+	}
+	items := []appsv1.Deployment{}
+	for _, v := range snap.items {
+		if s.matchesObject(v.ObjectMeta) {
+			items = append(items, *v.DeepCopy())
+		}
+	}
+	return items, age, true
+}
+
+// markStale records that the response for the request is being built from
+// a snapshot of the provided age.
+func markStale(ctx context.Context, age time.Duration) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.stale = true
+		info.age = age
+	}
+}
+
+// markUnavailable records the clusters that were left out of the response
+// for the request.
+func markUnavailable(ctx context.Context, clusters []string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok && len(clusters) > 0 {
+		info.unavailable = clusters
+	}
+}
+
+// writeStaleHeaders marks the response as stale if it was built from a
+// snapshot, with the age of the snapshot in the Age header, and lists any
+// clusters that were unavailable.
+func writeStaleHeaders(w http.ResponseWriter, r *http.Request) {
+	info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	if !ok {
+		return
+	}
+	if len(info.unavailable) > 0 {
+		w.Header().Set(unavailableHeader, strings.Join(info.unavailable, ","))
+	}
+	if !info.stale {
+		return
+	}
+	w.Header().Set(staleHeader, "true")
+	w.Header().Set("Age", strconv.Itoa(int(info.age.Seconds())))
+}
@@ -0,0 +1,157 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/bigkevmcd/peanut-backstage/test"
+)
+
+func TestStaleWhileError(t *testing.T) {
+	dep := test.NewDeployment("mysql", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "mysql"}),
+	)
+	var failing atomic.Bool
+	c := interceptor.NewClient(newFakeClient(t, &dep).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if failing.Load() {
+				return apierrors.NewServiceUnavailable("etcd is unavailable")
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	router := NewRouter(zapr.NewLogger(zap.NewNop()), c, WithMaxStaleness(time.Minute))
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	router.snapshots.now = func() time.Time { return now }
+	ts := httptest.NewTLSServer(router)
+	t.Cleanup(ts.Close)
+
+	get := func(path string) *http.Response {
+		t.Helper()
+		res, err := ts.Client().Do(makeClientRequest(t, ts, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	res := get("/backstage/catalog-info.yaml")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusOK)
+	}
+	if h := res.Header.Get(staleHeader); h != "" {
+		t.Fatalf("got %s %q for a fresh response", staleHeader, h)
+	}
+
+	failing.Store(true)
+	now = now.Add(30 * time.Second)
+	res = get("/backstage/catalog-info.yaml")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusOK)
+	}
+	if h := res.Header.Get(staleHeader); h != "true" {
+		t.Errorf("got %s %q, want true", staleHeader, h)
+	}
+	if h := res.Header.Get("Age"); h != "30" {
+		t.Errorf("got Age %q, want 30", h)
+	}
+	assertYAMLResponse(t, res, scopedLocation(DefaultLocationName, DefaultLocationDescription, "./component/mysql/info.yaml"))
+
+	// Components are resolved from the same snapshot.
+	res = get("/backstage/component/mysql/info.yaml")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusOK)
+	}
+
+	// Scopes are filtered from the unfiltered snapshot.
+	res = get("/backstage/namespaces/test-ns/catalog-info.yaml")
+	assertYAMLResponse(t, res, scopedLocation("namespace-test-ns", "Components in namespace test-ns", "./component/mysql/info.yaml"))
+	res = get("/backstage/namespaces/other-ns/catalog-info.yaml")
+	assertYAMLResponse(t, res, scopedLocation("namespace-other-ns", "Components in namespace other-ns"))
+	res = get("/backstage/catalog-info.yaml?fieldSelector=metadata.name%3Dnginx")
+	assertYAMLResponse(t, res, scopedLocation(DefaultLocationName, DefaultLocationDescription))
+
+	now = now.Add(31 * time.Second)
+	res = get("/backstage/catalog-info.yaml")
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestStaleWhileErrorDisabled(t *testing.T) {
+	var failing atomic.Bool
+	c := interceptor.NewClient(newFakeClient(t).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if failing.Load() {
+				return apierrors.NewServiceUnavailable("etcd is unavailable")
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	ts := newTestServer(t, c)
+
+	for _, want := range []int{http.StatusOK, http.StatusServiceUnavailable} {
+		res, err := ts.Client().Do(makeClientRequest(t, ts, "/backstage/catalog-info.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("got status %v, want %v", res.StatusCode, want)
+		}
+		failing.Store(true)
+	}
+}
+
+func TestStaleWhileErrorOnlySnapshotsUnfilteredLists(t *testing.T) {
+	dep := test.NewDeployment("mysql", "test-ns",
+		test.WithLabels(map[string]string{nameLabel: "mysql"}),
+	)
+	var failing atomic.Bool
+	c := interceptor.NewClient(newFakeClient(t, &dep).(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if failing.Load() {
+				return apierrors.NewServiceUnavailable("etcd is unavailable")
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	router := NewRouter(zapr.NewLogger(zap.NewNop()), c, WithMaxStaleness(time.Minute))
+	ts := httptest.NewTLSServer(router)
+	t.Cleanup(ts.Close)
+
+	for _, path := range []string{
+		"/backstage/namespaces/test-ns/catalog-info.yaml",
+		"/backstage/catalog-info.yaml?labelSelector=app%3Dtest",
+	} {
+		res, err := ts.Client().Do(makeClientRequest(t, ts, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if len(router.snapshots.snapshots) != 0 {
+		t.Fatalf("got %d snapshots from filtered lists, want 0", len(router.snapshots.snapshots))
+	}
+
+	failing.Store(true)
+	res, err := ts.Client().Do(makeClientRequest(t, ts, "/backstage/namespaces/test-ns/catalog-info.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusServiceUnavailable)
+	}
+}
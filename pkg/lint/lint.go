@@ -0,0 +1,226 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/backstage"
+	"github.com/bigkevmcd/peanut-backstage/pkg/catalog"
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+// Output formats for findings.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Formats are the supported output formats.
+var Formats = []string{FormatText, FormatJSON, FormatSARIF}
+
+// Finding is a Problem with the files that the objects were read from.
+type Finding struct {
+	backstage.Problem
+	Sources []string `json:"sources,omitempty"`
+}
+
+// Lint checks the objects in the sources that Components are discovered
+// from, other objects are ignored.
+//
+// Objects that weren't read from files e.g. from a cluster can be provided
+// in a Source with an empty Path.
+func Lint(sources []manifests.Source) []Finding {
+	objs := []unstructured.Unstructured{}
+	paths := map[backstage.ObjectReference][]string{}
+	for _, src := range sources {
+		for _, obj := range src.Objects {
+			if !catalog.IsDiscovered(obj) {
+				continue
+			}
+			objs = append(objs, obj)
+			ref := backstage.ObjectReference{Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+			if src.Path != "" && !slices.Contains(paths[ref], src.Path) {
+				paths[ref] = append(paths[ref], src.Path)
+			}
+		}
+	}
+
+	findings := []Finding{}
+	for _, p := range backstage.Lint(objs) {
+		f := Finding{Problem: p}
+		for _, ref := range p.Objects {
+			for _, path := range paths[ref] {
+				if !slices.Contains(f.Sources, path) {
+					f.Sources = append(f.Sources, path)
+				}
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// HasErrors returns true if any of the findings are errors.
+func HasErrors(findings []Finding) bool {
+	return slices.ContainsFunc(findings, func(f Finding) bool {
+		return f.Severity == backstage.SeverityError
+	})
+}
+
+// Write writes the findings to w in the format.
+func Write(w io.Writer, format string, findings []Finding) error {
+	switch format {
+	case FormatText:
+		return writeText(w, findings)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	case FormatSARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(toSARIF(findings))
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func writeText(w io.Writer, findings []Finding) error {
+	errorCount, warningCount := 0, 0
+	for _, f := range findings {
+		if f.Severity == backstage.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+		prefix := ""
+		if len(f.Sources) > 0 {
+			prefix = f.Sources[0] + ": "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s [%s] %s: %s\n", prefix, f.Severity, f.Rule, f.Objects[0], f.Message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d errors, %d warnings\n", errorCount, warningCount)
+	return err
+}
+
+// The following types are the subset of the SARIF 2.1.0 format that is
+// used to report findings.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration sarifConfig  `json:"defaultConfiguration"`
+}
+
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+func toSARIF(findings []Finding) sarifLog {
+	rules := []sarifRule{}
+	for _, r := range backstage.LintRules {
+		rules = append(rules, sarifRule{
+			ID:                   r.ID,
+			ShortDescription:     sarifMessage{Text: r.Description},
+			DefaultConfiguration: sarifConfig{Level: string(r.Severity)},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, f := range findings {
+		logical := []sarifLogicalLocation{}
+		for _, ref := range f.Objects {
+			logical = append(logical, sarifLogicalLocation{FullyQualifiedName: ref.String(), Kind: "resource"})
+		}
+		locations := []sarifLocation{}
+		for _, src := range f.Sources {
+			if src == manifests.Stdin {
+				continue
+			}
+			locations = append(locations, sarifLocation{
+				PhysicalLocation: &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(src)}},
+				LogicalLocations: logical,
+			})
+		}
+		if len(locations) == 0 {
+			locations = append(locations, sarifLocation{LogicalLocations: logical})
+		}
+		results = append(results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     string(f.Severity),
+			Message:   sarifMessage{Text: f.Message},
+			Locations: locations,
+		})
+	}
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{Driver: sarifDriver{
+					Name:           "peanut-backstage",
+					InformationURI: "https://github.com/bigkevmcd/peanut-backstage",
+					Rules:          rules,
+				}},
+				Results: results,
+			},
+		},
+	}
+}
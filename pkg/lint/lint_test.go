@@ -0,0 +1,118 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/manifests"
+)
+
+const manifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mysql
+  namespace: test-ns
+  labels:
+    app.kubernetes.io/name: mysql
+    app.kubernetes.io/component: database
+  annotations:
+    backstage.io/kubernetes-lifecycle: production
+    backstage.io/unknown: test
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: mysql
+  labels:
+    app.kubernetes.io/name: mysql
+`
+
+func TestLint(t *testing.T) {
+	findings := lintManifest(t)
+
+	if !HasErrors(findings) {
+		t.Error("expected errors to be found")
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatText, findings); err != nil {
+		t.Fatal(err)
+	}
+	want := `manifests/mysql.yaml: error [missing-owner] Deployment/test-ns/mysql: no app.kubernetes.io/created-by label, Backstage requires an owner
+manifests/mysql.yaml: warning [unknown-annotation] Deployment/test-ns/mysql: unknown annotation "backstage.io/unknown"
+1 errors, 1 warnings
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("failed to write text:\n%s", diff)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, lintManifest(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"rule":      "missing-owner",
+		"severity":  "error",
+		"message":   "no app.kubernetes.io/created-by label, Backstage requires an owner",
+		"component": "mysql",
+		"objects":   []any{map[string]any{"kind": "Deployment", "namespace": "test-ns", "name": "mysql"}},
+		"sources":   []any{"manifests/mysql.yaml"},
+	}
+	if diff := cmp.Diff(want, got[0]); diff != "" {
+		t.Fatalf("failed to write JSON:\n%s", diff)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatSARIF, lintManifest(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("got version %q with %d runs", got.Version, len(got.Runs))
+	}
+	want := sarifResult{
+		RuleID:  "missing-owner",
+		Level:   "error",
+		Message: sarifMessage{Text: "no app.kubernetes.io/created-by label, Backstage requires an owner"},
+		Locations: []sarifLocation{
+			{
+				PhysicalLocation: &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "manifests/mysql.yaml"}},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "Deployment/test-ns/mysql", Kind: "resource"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got.Runs[0].Results[0]); diff != "" {
+		t.Fatalf("failed to write SARIF:\n%s", diff)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func lintManifest(t *testing.T) []Finding {
+	t.Helper()
+	objs, err := manifests.Decode(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Lint([]manifests.Source{{Path: "manifests/mysql.yaml", Objects: objs}})
+}
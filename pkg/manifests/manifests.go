@@ -0,0 +1,150 @@
+package manifests
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Stdin is the path that reads manifests from standard input.
+const Stdin = "-"
+
+// manifestExtensions are the extensions of the files that are read from
+// directories.
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+// Decode reads the YAML or JSON documents from r and decodes them into
+// objects.
+//
+// Documents can be separated with "---", and lists of objects e.g. the
+// output of "kubectl get -o yaml" are flattened into their items. Empty
+// documents are skipped.
+func Decode(r io.Reader) ([]unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(r), 4096)
+	result := []unstructured.Unstructured{}
+	for {
+		obj := map[string]any{}
+		if err := decoder.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return nil, fmt.Errorf("failed to decode manifest: %w", err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		u := unstructured.Unstructured{Object: obj}
+		if !u.IsList() {
+			if u.GetKind() == "" {
+				return nil, fmt.Errorf("failed to decode manifest: object has no kind")
+			}
+			result = append(result, u)
+			continue
+		}
+		list, err := u.ToList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode list: %w", err)
+		}
+		result = append(result, list.Items...)
+	}
+}
+
+// Source is the objects that were decoded from a file, or stdin.
+type Source struct {
+	// Path is the path of the file, or Stdin.
+	Path    string
+	Objects []unstructured.Unstructured
+}
+
+// Read decodes the manifests from the paths, which can be files,
+// directories, which are read recursively, or Stdin to read from stdin.
+func Read(paths []string, stdin io.Reader) ([]unstructured.Unstructured, error) {
+	sources, err := ReadSources(paths, stdin)
+	if err != nil {
+		return nil, err
+	}
+	result := []unstructured.Unstructured{}
+	for _, v := range sources {
+		result = append(result, v.Objects...)
+	}
+	return result, nil
+}
+
+// ReadSources decodes the manifests from the paths in the same way as Read,
+// keeping track of the file that each object was read from.
+func ReadSources(paths []string, stdin io.Reader) ([]Source, error) {
+	result := []Source{}
+	for _, p := range paths {
+		if p == Stdin {
+			objs, err := Decode(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read stdin: %w", err)
+			}
+			result = append(result, Source{Path: Stdin, Objects: objs})
+			continue
+		}
+
+		files, err := Files(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			objs, err := readFile(f)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, Source{Path: f, Objects: objs})
+		}
+	}
+	return result, nil
+}
+
+func readFile(filename string) ([]unstructured.Unstructured, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	objs, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	return objs, nil
+}
+
+// Files returns the path if it's a file, or the YAML and JSON files in the
+// directory and its subdirectories, sorted by path.
+func Files(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && slices.Contains(manifestExtensions, strings.ToLower(filepath.Ext(p))) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", path, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,116 @@
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const deploymentManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: web
+`
+
+func TestDecode(t *testing.T) {
+	decodeTests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"single document", deploymentManifest, []string{"Deployment/web/nginx"}},
+		{
+			"multiple documents",
+			"---\n" + deploymentManifest + "---\n# empty\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: nginx\n",
+			[]string{"Deployment/web/nginx", "Service//nginx"},
+		},
+		{
+			"json",
+			`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "nginx", "namespace": "web"}}`,
+			[]string{"Deployment/web/nginx"},
+		},
+		{
+			"list",
+			"apiVersion: v1\nkind: List\nitems:\n- apiVersion: apps/v1\n  kind: Deployment\n  metadata:\n    name: mysql\n- apiVersion: example.com/v1\n  kind: Widget\n  metadata:\n    name: test\n",
+			[]string{"Deployment//mysql", "Widget//test"},
+		},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := Decode(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, objectKeys(objs)); diff != "" {
+				t.Fatalf("failed to decode:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	errorTests := []struct {
+		name  string
+		input string
+	}{
+		{"invalid yaml", "kind: [Deployment\n"},
+		{"missing kind", "apiVersion: v1\nmetadata:\n  name: test\n"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(strings.NewReader(tt.input)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "b", "mysql.yml"), "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: mysql\n")
+	writeFile(t, filepath.Join(dir, "a.yaml"), deploymentManifest)
+	writeFile(t, filepath.Join(dir, "README.md"), "# Not a manifest\n")
+	single := filepath.Join(t.TempDir(), "redis.json")
+	writeFile(t, single, `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "redis"}}`)
+
+	objs, err := Read([]string{dir, single, Stdin}, strings.NewReader("apiVersion: v1\nkind: Service\nmetadata:\n  name: nginx\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Deployment/web/nginx", "Deployment//mysql", "Deployment//redis", "Service//nginx"}
+	if diff := cmp.Diff(want, objectKeys(objs)); diff != "" {
+		t.Fatalf("failed to read:\n%s", diff)
+	}
+}
+
+func TestReadMissingPath(t *testing.T) {
+	if _, err := Read([]string{filepath.Join(t.TempDir(), "missing.yaml")}, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func objectKeys(objs []unstructured.Unstructured) []string {
+	result := []string{}
+	for _, v := range objs {
+		result = append(result, v.GetKind()+"/"+v.GetNamespace()+"/"+v.GetName())
+	}
+	return result
+}
+
+func writeFile(t *testing.T, filename, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "peanut_backstage"
+
+// Metrics records metrics for the server.
+//
+// All methods can be called on a nil *Metrics, in which case nothing is
+// recorded.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	parseDuration   prometheus.Histogram
+	entities        *prometheus.GaugeVec
+	diagnostics     *prometheus.CounterVec
+	listErrors      *prometheus.CounterVec
+	clusters        *prometheus.GaugeVec
+}
+
+// New creates and returns a new Metrics with its own registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Count of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		parseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "parse_duration_seconds",
+			Help:      "Duration of parsing Kubernetes resources into Backstage entities.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		entities: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "entities",
+			Help:      "Number of entities in the catalog by kind.",
+		}, []string{"kind"}),
+		diagnostics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "diagnostics_total",
+			Help:      "Count of problems building the catalog by reason.",
+		}, []string{"reason"}),
+		listErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kubernetes_list_errors_total",
+			Help:      "Count of errors listing resources from Kubernetes by resource.",
+		}, []string{"resource"}),
+		clusters: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cluster_available",
+			Help:      "Whether resources could be listed from each cluster when it was last listed.",
+		}, []string{"cluster"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.parseDuration,
+		m.entities,
+		m.diagnostics,
+		m.listErrors,
+		m.clusters,
+	)
+
+	return m
+}
+
+// Handler returns an http.Handler that serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a request to a route.
+func (m *Metrics) ObserveRequest(route, method string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	m.requestDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// ObserveParse records the time taken to parse resources.
+func (m *Metrics) ObserveParse(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.parseDuration.Observe(d.Seconds())
+}
+
+// SetEntities records the number of entities of a kind in the catalog.
+func (m *Metrics) SetEntities(kind string, n int) {
+	if m == nil {
+		return
+	}
+	m.entities.WithLabelValues(kind).Set(float64(n))
+}
+
+// Diagnostic records a problem building the catalog.
+func (m *Metrics) Diagnostic(reason string) {
+	if m == nil {
+		return
+	}
+	m.diagnostics.WithLabelValues(reason).Inc()
+}
+
+// ListError records an error listing a resource from Kubernetes.
+func (m *Metrics) ListError(resource string) {
+	if m == nil {
+		return
+	}
+	m.listErrors.WithLabelValues(resource).Inc()
+}
+
+// SetClusterAvailable records whether resources could be listed from a
+// cluster.
+func (m *Metrics) SetClusterAvailable(cluster string, available bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if available {
+		v = 1
+	}
+	m.clusters.WithLabelValues(cluster).Set(v)
+}
@@ -0,0 +1,158 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Reloader provides a tls.Config for serving, reloading the certificate,
+// key and client CA files when they change.
+//
+// This allows certificates to be rotated e.g. by cert-manager without
+// restarting the server.
+type Reloader struct {
+	certFile          string
+	keyFile           string
+	clientCAFile      string
+	requireClientCert bool
+
+	logger   logr.Logger
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+// NewReloader creates and returns a new Reloader, loading the files.
+//
+// The clientCAFile is optional, if requireClientCert is true, clients must
+// present a certificate signed by the client CA, otherwise client
+// certificates are only verified if they are presented.
+func NewReloader(l logr.Logger, certFile, keyFile, clientCAFile string, requireClientCert bool) (*Reloader, error) {
+	r := &Reloader{
+		certFile:          certFile,
+		keyFile:           keyFile,
+		clientCAFile:      clientCAFile,
+		requireClientCert: requireClientCert,
+		logger:            l,
+		modTimes:          map[string]time.Time{},
+	}
+	if requireClientCert && clientCAFile == "" {
+		return nil, errors.New("a client CA file is required to require client certificates")
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// nextProtos are the protocols negotiated with ALPN, the config returned for
+// each client replaces the server's, so HTTP/2 must be offered explicitly.
+var nextProtos = []string{"h2", "http/1.1"}
+
+// Config returns a tls.Config that uses the most recently loaded
+// certificates.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCA != nil {
+				cfg.ClientCAs = r.clientCA
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.requireClientCert {
+					cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// Start polls the files for changes at the interval until the context is
+// cancelled.
+func (r *Reloader) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				// Keep serving the previous certificates, the files may be
+				// part way through being updated.
+				r.logger.Error(err, "failed to reload certificates")
+				continue
+			}
+			if reloaded {
+				r.logger.Info("reloaded certificates")
+			}
+		}
+	}
+}
+
+// reload loads the files if they have changed since they were last loaded.
+func (r *Reloader) reload() (bool, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	modTimes := map[string]time.Time{}
+	changed := false
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", f, err)
+		}
+		modTimes[f] = info.ModTime()
+		if !info.ModTime().Equal(r.modTimes[f]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.clientCAFile != "" {
+		b, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return false, fmt.Errorf("no certificates found in client CA %s", r.clientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+
+	return true, nil
+}
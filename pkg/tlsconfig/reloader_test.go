@@ -0,0 +1,190 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+)
+
+func TestReloaderReloadsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certFile, keyFile, newCertificate(t, "first.example.com", nil))
+
+	r, err := NewReloader(zapr.NewLogger(zap.NewNop()), certFile, keyFile, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertServedCertificate(t, r, "first.example.com")
+
+	writeKeyPair(t, certFile, keyFile, newCertificate(t, "second.example.com", nil))
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := r.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded {
+		t.Fatal("certificate was not reloaded")
+	}
+	assertServedCertificate(t, r, "second.example.com")
+
+	reloaded, err = r.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded {
+		t.Fatal("certificate was reloaded without changes")
+	}
+}
+
+func TestReloaderRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := newCertificate(t, "Test CA", nil)
+	writeKeyPair(t, certFile, keyFile, newCertificate(t, "127.0.0.1", nil))
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReloader(zapr.NewLogger(zap.NewNop()), certFile, keyFile, caFile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = r.Config()
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	clientTLS := &tls.Config{InsecureSkipVerify: true}
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Fatal("expected request without client certificate to fail")
+	}
+
+	clientTLS.Certificates = []tls.Certificate{newCertificate(t, "backstage", &ca)}
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v, want %v", res.StatusCode, http.StatusOK)
+	}
+}
+
+func TestReloaderNegotiatesHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certFile, keyFile, newCertificate(t, "127.0.0.1", nil))
+
+	r, err := NewReloader(zapr.NewLogger(zap.NewNop()), certFile, keyFile, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.EnableHTTP2 = true
+	ts.TLS = r.Config()
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	c := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.ProtoMajor != 2 {
+		t.Fatalf("got protocol %s, want HTTP/2", res.Proto)
+	}
+}
+
+func TestNewReloaderRequiresClientCA(t *testing.T) {
+	_, err := NewReloader(zapr.NewLogger(zap.NewNop()), "tls.crt", "tls.key", "", true)
+	if err == nil {
+		t.Fatal("expected an error without a client CA")
+	}
+}
+
+func assertServedCertificate(t *testing.T, r *Reloader, want string) {
+	t.Helper()
+	cfg, err := r.Config().GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != want {
+		t.Fatalf("got certificate for %q, want %q", cert.Subject.CommonName, want)
+	}
+}
+
+// newCertificate creates a certificate for the common name, signed by the
+// parent, or self-signed if parent is nil.
+func newCertificate(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signerKey := template, any(key)
+	if parent != nil {
+		signer, err = x509.ParseCertificate(parent.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signerKey = parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writeKeyPair(t *testing.T, certFile, keyFile string, cert tls.Certificate) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
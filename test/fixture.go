@@ -0,0 +1,35 @@
+package test
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bigkevmcd/peanut-backstage/pkg/fixture"
+)
+
+// NewFixtureClient creates and returns a fake client serving the objects in
+// a fixture file, recorded with the record command.
+func NewFixtureClient(t *testing.T, filename string) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	objs, err := fixture.Read(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := fixture.NewClient(scheme, objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}